@@ -3,6 +3,7 @@ module github.com/bamf0/toolbox
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/spf13/cobra v1.8.0
 	gopkg.in/yaml.v3 v3.0.1
 )
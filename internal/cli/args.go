@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// argsPlaceholder matches a word that is nothing but {{args}}, which expands
+// to any number of arguments
+var argsPlaceholder = regexp.MustCompile(`^\{\{-?\s*args\s*-?\}\}$`)
+
+// argBinder hands command-line arguments to the {{args}} and {{arg N}}
+// placeholders of one command and records which of them were used
+type argBinder struct {
+	args   []string
+	params []string
+	used   []bool
+
+	// bound is set once any placeholder has been expanded
+	bound bool
+
+	// spread is set when {{args}} takes the arguments no {{arg N}} used
+	spread bool
+}
+
+// missingArgError is returned by {{arg N}} when the user gave fewer
+// arguments than the command needs
+type missingArgError struct {
+	position int
+	param    string
+}
+
+func (e *missingArgError) Error() string {
+	if e.param != "" {
+		return fmt.Sprintf("missing argument %d (%s)", e.position, e.param)
+	}
+	return fmt.Sprintf("missing argument %d", e.position)
+}
+
+// newArgBinder creates a binder for the user arguments of a command whose
+// positional parameters are named params
+func newArgBinder(params, args []string) *argBinder {
+	return &argBinder{args: args, params: params, used: make([]bool, len(args))}
+}
+
+// index resolves a 1-based position or a parameter name to an argument index
+func (b *argBinder) index(ref interface{}) (int, error) {
+	switch ref := ref.(type) {
+	case int:
+		if ref < 1 {
+			return 0, fmt.Errorf("argument positions start at 1, got %d", ref)
+		}
+		return ref - 1, nil
+	case string:
+		for i, param := range b.params {
+			if param == ref {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown parameter %q", ref)
+	default:
+		return 0, fmt.Errorf("arg takes a position or a parameter name, got %v", ref)
+	}
+}
+
+// arg returns one argument by position or parameter name
+func (b *argBinder) arg(ref interface{}) (string, error) {
+	i, err := b.index(ref)
+	if err != nil {
+		return "", err
+	}
+	b.bound = true
+	if i >= len(b.args) {
+		err := &missingArgError{position: i + 1}
+		if i < len(b.params) {
+			err.param = b.params[i]
+		}
+		return "", err
+	}
+	b.used[i] = true
+	return b.args[i], nil
+}
+
+// rest returns the arguments that {{args}} expands to: every argument no
+// {{arg N}} placeholder used
+func (b *argBinder) rest() []string {
+	b.bound = true
+	b.spread = true
+	return b.remaining()
+}
+
+// remaining returns the arguments no {{arg N}} placeholder used, without
+// marking them as taken
+func (b *argBinder) remaining() []string {
+	var rest []string
+	for i, arg := range b.args {
+		if !b.used[i] {
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// unused returns an error for arguments no placeholder used
+func (b *argBinder) unused() error {
+	if b.spread {
+		return nil
+	}
+	var extra []string
+	for i, arg := range b.args {
+		if !b.used[i] {
+			extra = append(extra, fmt.Sprintf("%q", arg))
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	return withExitCode(ExitUsage, fmt.Errorf("unexpected arguments: %s (the command takes its arguments through placeholders)", strings.Join(extra, ", ")))
+}
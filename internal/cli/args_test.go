@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// TestExpandCommand_Args tests placing user arguments with {{args}} and {{arg N}}
+func TestExpandCommand_Args(t *testing.T) {
+	data := &templateData{Env: map[string]string{}, Vars: map[string]string{}}
+
+	tests := []struct {
+		name     string
+		command  config.Command
+		userArgs []string
+		want     []string
+		errMsg   string
+	}{
+		{
+			name:     "no placeholders appends",
+			command:  config.Command{Run: "go test ./..."},
+			userArgs: []string{"-run", "TestFoo"},
+			want:     []string{"go", "test", "./...", "-run", "TestFoo"},
+		},
+		{
+			name:     "args in the middle",
+			command:  config.Command{Run: "docker run {{args}} myimage"},
+			userArgs: []string{"-p", "8080:80"},
+			want:     []string{"docker", "run", "-p", "8080:80", "myimage"},
+		},
+		{
+			name:    "args with no arguments",
+			command: config.Command{Run: "docker run {{ args }} myimage"},
+			want:    []string{"docker", "run", "myimage"},
+		},
+		{
+			name:     "arg by position",
+			command:  config.Command{Run: "kubectl logs -f {{arg 1}} -n prod"},
+			userArgs: []string{"api-7f9c"},
+			want:     []string{"kubectl", "logs", "-f", "api-7f9c", "-n", "prod"},
+		},
+		{
+			name:     "arg by name inside a word",
+			command:  config.Command{Run: `kubectl logs {{arg "pod"}} --namespace={{arg "namespace"}}`, Params: []string{"pod", "namespace"}},
+			userArgs: []string{"api", "my ns"},
+			want:     []string{"kubectl", "logs", "api", "--namespace=my ns"},
+		},
+		{
+			name:     "args takes what arg N left",
+			command:  config.Command{Run: "kubectl {{args}} logs {{arg 1}}"},
+			userArgs: []string{"api", "--tail", "10"},
+			want:     []string{"kubectl", "--tail", "10", "logs", "api"},
+		},
+		{
+			name:     "placeholders in fixed args",
+			command:  config.Command{Run: "helm upgrade", Args: []string{"{{arg 1}}", "{{args}}"}},
+			userArgs: []string{"web", "--dry-run"},
+			want:     []string{"helm", "upgrade", "web", "--dry-run"},
+		},
+		{
+			name:     "unused arguments are an error",
+			command:  config.Command{Run: "kubectl logs {{arg 1}}"},
+			userArgs: []string{"api", "extra"},
+			errMsg:   `unexpected arguments: "extra"`,
+		},
+		{
+			name:     "unknown parameter",
+			command:  config.Command{Run: `kubectl logs {{arg "pod"}}`},
+			userArgs: []string{"api"},
+			errMsg:   `unknown parameter "pod"`,
+		},
+		{
+			name:     "args inside a word",
+			command:  config.Command{Run: "echo --flags={{args}}"},
+			userArgs: []string{"a"},
+			errMsg:   "{{args}} must be a word of its own",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := expandCommand(tt.command, data, tt.userArgs)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("expandCommand() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandCommand() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExpandCommand_MissingArg tests that a missing argument is reported as
+// a plain usage error, in command lines, fixed args and scripts alike
+func TestExpandCommand_MissingArg(t *testing.T) {
+	data := &templateData{Env: map[string]string{}, Vars: map[string]string{}}
+
+	tests := []struct {
+		name    string
+		command config.Command
+		want    string
+	}{
+		{
+			name:    "named parameter",
+			command: config.Command{Run: `kubectl logs {{arg "pod"}}`, Params: []string{"pod"}},
+			want:    "missing argument 1 (pod)",
+		},
+		{
+			name:    "position without a name",
+			command: config.Command{Run: "helm upgrade", Args: []string{"{{arg 2}}"}},
+			want:    "missing argument 2",
+		},
+		{
+			name:    "script",
+			command: config.Command{Run: "cd {{arg 1}}", Shell: "sh", Params: []string{"dir"}},
+			want:    "missing argument 1 (dir)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.command.Shell != "" {
+				_, _, _, err = expandScript(tt.command, data, nil)
+			} else {
+				_, _, err = expandCommand(tt.command, data, nil)
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err.Error(), tt.want)
+			}
+			if code := ExitCode(err); code != ExitUsage {
+				t.Errorf("ExitCode() = %d, want %d", code, ExitUsage)
+			}
+		})
+	}
+}
+
+// TestExpandScript_Args tests that placeholders in scripts are shell-quoted
+func TestExpandScript_Args(t *testing.T) {
+	data := &templateData{Env: map[string]string{}, Vars: map[string]string{}}
+
+	tests := []struct {
+		name           string
+		command        config.Command
+		userArgs       []string
+		wantScript     string
+		wantPositional []string
+	}{
+		{
+			name:           "no placeholders passes positional parameters",
+			command:        config.Command{Run: `echo "$@"`, Shell: "sh"},
+			userArgs:       []string{"a b"},
+			wantScript:     `echo "$@"`,
+			wantPositional: []string{"a b"},
+		},
+		{
+			name:       "placeholders consume the arguments",
+			command:    config.Command{Run: "cd {{arg 1}} && make {{args}}", Shell: "sh"},
+			userArgs:   []string{"my dir", "all", "it's"},
+			wantScript: `cd 'my dir' && make 'all' 'it'\''s'`,
+		},
+		{
+			name:           "arguments {{arg N}} leaves are positional parameters",
+			command:        config.Command{Run: `cd {{arg 1}} && make "$@"`, Shell: "sh"},
+			userArgs:       []string{"my dir", "all", "it's"},
+			wantScript:     `cd 'my dir' && make "$@"`,
+			wantPositional: []string{"all", "it's"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, positional, _, err := expandScript(tt.command, data, tt.userArgs)
+			if err != nil {
+				t.Fatalf("expandScript() unexpected error: %v", err)
+			}
+			if script != tt.wantScript {
+				t.Errorf("expandScript() script = %q, want %q", script, tt.wantScript)
+			}
+			if !reflect.DeepEqual(positional, tt.wantPositional) {
+				t.Errorf("expandScript() positional = %q, want %q", positional, tt.wantPositional)
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/bamf0/toolbox/internal/config"
-	contextpkg "github.com/bamf0/toolbox/internal/context"
 	"github.com/spf13/cobra"
 )
 
@@ -38,54 +38,26 @@ func setupCompletion() {
 func getDynamicCommandCompletions(toComplete string) []string {
 	var suggestions []string
 
-	// Check if --context flag is set
-	var detectedCtx string
-	if forceCtx != "" {
-		detectedCtx = forceCtx
-	} else {
-		// Try plugin-based detection first
+	// Load config, which may define how contexts are detected
+	cfg, err := config.Load("")
+	if err == nil {
+		// Merge plugin contexts
 		pm := getPluginManager()
-		pluginCtx, _, foundByPlugin := pm.DetectContext(".")
-
-		if foundByPlugin {
-			detectedCtx = pluginCtx
-		} else {
-			// Fall back to built-in detection
-			detector := contextpkg.NewDetector()
-			ctx, err := detector.Detect(".")
-			if err == nil {
-				detectedCtx = ctx
-			}
-		}
-	}
-
-	if detectedCtx != "" {
-		// Load config and get commands for detected context
-		cfg, err := config.Load("")
-		if err == nil {
-			// Merge plugin contexts
-			pm := getPluginManager()
-			pluginContexts := pm.GetContexts()
-			for ctxName, ctxConfig := range pluginContexts {
-				if _, exists := cfg.Contexts[ctxName]; !exists {
-					cfg.Contexts[ctxName] = ctxConfig
-				}
-			}
-
-			// Get commands from detected context
-			if ctxConfig, exists := cfg.Contexts[detectedCtx]; exists {
-				for cmdName := range ctxConfig.Commands {
-					if strings.HasPrefix(cmdName, toComplete) {
-						// Add command with description if available
-						description := ""
-						if desc, ok := ctxConfig.Descriptions[cmdName]; ok {
-							description = "\t" + desc
-						}
-						suggestions = append(suggestions, cmdName+description)
-					}
+		addPluginContexts(cfg, pm)
+
+		// Use the --context flag if set, otherwise every detected context,
+		// as commands are looked up through all of them
+		contexts := []string{forceCtx}
+		if forceCtx == "" {
+			contexts = nil
+			if candidates, err := detectContexts(cfg, pm); err == nil {
+				for _, c := range candidates {
+					contexts = append(contexts, c.Context)
 				}
 			}
 		}
+
+		suggestions = commandCompletions(cfg, contexts, toComplete)
 	}
 
 	// If no context-specific suggestions, add common commands
@@ -101,6 +73,39 @@ func getDynamicCommandCompletions(toComplete string) []string {
 	return suggestions
 }
 
+// commandCompletions returns the commands of contexts, best first, and of
+// the global context that start with toComplete, sorted and each with the
+// description of the context that would run it
+func commandCompletions(cfg *config.Config, contexts []string, toComplete string) []string {
+	if len(contexts) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var suggestions []string
+	for _, ctxName := range append(contexts, config.GlobalContext) {
+		ctxConfig, exists := cfg.Contexts[ctxName]
+		if !exists {
+			continue
+		}
+		for cmdName := range ctxConfig.Commands {
+			if seen[cmdName] || !strings.HasPrefix(cmdName, toComplete) {
+				continue
+			}
+			seen[cmdName] = true
+
+			// Add command with description if available
+			description := ""
+			if desc := ctxConfig.Description(cmdName); desc != "" {
+				description = "\t" + desc
+			}
+			suggestions = append(suggestions, cmdName+description)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
 // getContextCompletions returns all available contexts for completion
 func getContextCompletions(toComplete string) []string {
 	var suggestions []string
@@ -113,12 +118,7 @@ func getContextCompletions(toComplete string) []string {
 
 	// Merge plugin contexts
 	pm := getPluginManager()
-	pluginContexts := pm.GetContexts()
-	for ctxName, ctxConfig := range pluginContexts {
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			cfg.Contexts[ctxName] = ctxConfig
-		}
-	}
+	addPluginContexts(cfg, pm)
 
 	// Get all context names
 	for ctxName := range cfg.Contexts {
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
 )
 
 // TestCompletion_BashGeneration tests bash completion generation
@@ -173,6 +175,165 @@ func TestGetDynamicCommandCompletions(t *testing.T) {
 	}
 }
 
+// TestCompletion_ConfigDetectedContext tests completing commands of a context detected through its detect section
+func TestCompletion_ConfigDetectedContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+
+	files := map[string]string{
+		"deno.json": "{}",
+		".toolbox.yaml": `contexts:
+  deno:
+    detect:
+      markers: [deno.json, deno.jsonc]
+    commands:
+      cache: deno cache main.ts
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	os.Chdir(tmpDir)
+
+	suggestions := getDynamicCommandCompletions("ca")
+	if len(suggestions) != 1 || suggestions[0] != "cache" {
+		t.Errorf("getDynamicCommandCompletions(\"ca\") = %v, want [cache]", suggestions)
+	}
+}
+
+// TestCompletion_PackageScripts tests completing the scripts of package.json with their bodies as descriptions
+func TestCompletion_PackageScripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+
+	files := map[string]string{
+		"package.json":   `{"scripts": {"test:unit": "vitest run", "typecheck": "tsc --noEmit"}}`,
+		"pnpm-lock.yaml": "lockfileVersion: '9.0'\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	os.Chdir(tmpDir)
+
+	tests := []struct {
+		toComplete string
+		want       []string
+	}{
+		{"test", []string{"test\tRun tests (pnpm test)", "test:unit\tvitest run"}},
+		{"ty", []string{"typecheck\ttsc --noEmit"}},
+	}
+
+	for _, tt := range tests {
+		got := getDynamicCommandCompletions(tt.toComplete)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("getDynamicCommandCompletions(%q) = %q, want %q", tt.toComplete, got, tt.want)
+		}
+	}
+}
+
+// TestCompletion_CargoWorkspace tests completing the crates and binaries of a Cargo workspace
+func TestCompletion_CargoWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+
+	files := map[string]string{
+		"Cargo.toml":             "[workspace]\nmembers = [\"api\", \"core\"]\n",
+		"api/Cargo.toml":         "[package]\nname = \"api\"\n",
+		"api/src/main.rs":        "fn main() {}\n",
+		"api/src/bin/migrate.rs": "fn main() {}\n",
+		"core/Cargo.toml":        "[package]\nname = \"core\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directories: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	os.Chdir(tmpDir)
+
+	tests := []struct {
+		toComplete string
+		want       []string
+	}{
+		{"test:", []string{"test:api\tTest the api crate (cargo test -p api)", "test:core\tTest the core crate (cargo test -p core)"}},
+		{"run:m", []string{"run:migrate\tRun migrate (cargo run -p api --bin migrate)"}},
+	}
+
+	for _, tt := range tests {
+		got := getDynamicCommandCompletions(tt.toComplete)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("getDynamicCommandCompletions(%q) = %q, want %q", tt.toComplete, got, tt.want)
+		}
+	}
+}
+
+// TestCommandCompletions tests that commands of every detected context and of the global context are completed once
+func TestCommandCompletions(t *testing.T) {
+	cfg := &config.Config{
+		Contexts: map[string]config.ContextConfig{
+			"go": {
+				Commands:     map[string]config.Command{"test": {Run: "go test ./..."}},
+				Descriptions: map[string]string{"test": "Run Go tests"},
+			},
+			"docker": {
+				Commands: map[string]config.Command{"test": {Run: "docker compose run test"}, "up": {Run: "docker compose up"}},
+			},
+			config.GlobalContext: {
+				Commands: map[string]config.Command{"ci": {Steps: []string{"test"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		contexts   []string
+		toComplete string
+		want       []string
+	}{
+		{
+			name:     "best context wins",
+			contexts: []string{"go", "docker"},
+			want:     []string{"ci", "test\tRun Go tests", "up"},
+		},
+		{
+			name:       "prefix",
+			contexts:   []string{"docker", "go"},
+			toComplete: "t",
+			want:       []string{"test"},
+		},
+		{
+			name:     "unknown context",
+			contexts: []string{"rust"},
+			want:     []string{"ci"},
+		},
+		{
+			name: "no context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commandCompletions(cfg, tt.contexts, tt.toComplete)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("commandCompletions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestGetContextCompletions tests context completion
 func TestGetContextCompletions(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/bamf0/toolbox/internal/plugin"
+)
+
+// newDetector returns a context detector that also uses the detect sections
+// of the configuration and asks the loaded plugins, so that every command
+// ranks contexts the same way
+func newDetector(cfg *config.Config, pm *plugin.PluginManager) *contextpkg.Detector {
+	detector := contextpkg.NewDetector()
+	for _, p := range pm.GetPlugins() {
+		detector.AddSource(p)
+		if provider, ok := p.(plugin.RuleProvider); ok {
+			for ctx, rules := range provider.DetectRules() {
+				for _, rule := range rules {
+					// Rules were validated when the plugin was registered
+					detector.AddRule(ctx, rule)
+				}
+			}
+		}
+	}
+
+	if cfg != nil {
+		for ctx, ctxConfig := range cfg.Contexts {
+			// Detection settings were validated when the config was loaded
+			for _, rule := range ctxConfig.Detect.DetectRules() {
+				detector.AddRule(ctx, rule)
+			}
+			if ctxConfig.Detect.Priority != 0 {
+				detector.SetPriority(ctx, ctxConfig.Detect.Priority)
+			}
+		}
+	}
+	return detector
+}
+
+// detectContext returns the best ranked context for the current directory
+func detectContext(cfg *config.Config, pm *plugin.PluginManager) (contextpkg.Candidate, error) {
+	return newDetector(cfg, pm).DetectBest(".")
+}
+
+// detectContexts returns every context detected for the current directory,
+// best first, or an error if there is none
+func detectContexts(cfg *config.Config, pm *plugin.PluginManager) ([]contextpkg.Candidate, error) {
+	candidates, err := newDetector(cfg, pm).DetectAll(".")
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		absDir, _ := filepath.Abs(".")
+		return nil, fmt.Errorf("%w in %s or parent directories", contextpkg.ErrNoContext, absDir)
+	}
+	return candidates, nil
+}
+
+// lookupChain returns the contexts a command is looked up in, as
+// resolutionChain orders them, and the detected candidates. A forced context
+// is the only one looked in; it still runs from where it is detected, if it
+// is, so the candidates are returned as well.
+func lookupChain(cfg *config.Config, pm *plugin.PluginManager) ([][]string, []contextpkg.Candidate, error) {
+	if forceCtx != "" {
+		candidates, _ := newDetector(cfg, pm).DetectAll(".")
+		return [][]string{{forceCtx}}, candidates, nil
+	}
+
+	// Plugins and marker files are ranked together
+	candidates, err := detectContexts(cfg, pm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolutionChain(candidates), candidates, nil
+}
+
+// resolutionChain orders the contexts a command is looked up in: the best
+// ranked context alone, then the other candidates in tiers of equal score,
+// distance and priority. Within a tier, only the plugin and built-in order
+// tell contexts apart, so a command several of them define is reported as
+// ambiguous.
+func resolutionChain(candidates []contextpkg.Candidate) [][]string {
+	var chain [][]string
+	for i, c := range candidates {
+		if i > 1 {
+			prev := candidates[i-1]
+			if c.Score == prev.Score && c.Distance == prev.Distance && c.Priority == prev.Priority {
+				last := len(chain) - 1
+				chain[last] = append(chain[last], c.Context)
+				continue
+			}
+		}
+		chain = append(chain, []string{c.Context})
+	}
+	return chain
+}
+
+// hasCandidate reports whether ctx is among the detected candidates
+func hasCandidate(candidates []contextpkg.Candidate, ctx string) bool {
+	for _, c := range candidates {
+		if c.Context == ctx {
+			return true
+		}
+	}
+	return false
+}
+
+// projectDir returns the directory the commands of a context run in: where
+// the context was detected or, for a command from the global context, where
+// the detected or forced context was. It is "" for the current directory and
+// for a forced context that is not detected.
+func projectDir(candidates []contextpkg.Candidate, ctx, detectedCtx string) string {
+	var dir string
+	for _, name := range []string{ctx, detectedCtx} {
+		for _, c := range candidates {
+			if c.Context == name {
+				dir = c.Dir
+				break
+			}
+		}
+		if dir != "" {
+			break
+		}
+	}
+
+	if wd, err := filepath.Abs("."); err == nil && wd == dir {
+		return ""
+	}
+	return dir
+}
+
+// detectedFrom describes what a candidate was detected from, such as
+// "go.mod, go.sum" or "plugin: docker", with the directory if it is not the
+// current one
+func detectedFrom(c contextpkg.Candidate) string {
+	from := strings.Join(c.Markers, ", ")
+	if c.Plugin != "" {
+		from = "plugin: " + c.Plugin
+	}
+	if c.Distance > 0 {
+		from += " in " + relativeDir(c.Dir)
+	}
+	return from
+}
+
+// relativeDir shows dir relative to the working directory when it can
+func relativeDir(dir string) string {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		return dir
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		return dir
+	}
+	return rel
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// explainDetection makes tb detect show every check it made
+	explainDetection bool
+
+	// detectJSON makes tb detect print JSON
+	detectJSON bool
+)
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Show the detected context and why it was picked",
+	Long: `Print the context tb uses in the current directory.
+
+With --explain, also list the configuration files that were loaded, any
+--context override, every directory searched, every plugin and marker checked
+there, the ranked candidates and the final decision.
+
+Examples:
+  tb detect                    # Print the context name
+  tb detect --explain          # Show how it was detected
+  tb detect --explain --json   # The same, as JSON`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDetect(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	detectCmd.Flags().BoolVar(&explainDetection, "explain", false, "show every directory, plugin and marker checked")
+	detectCmd.Flags().BoolVar(&detectJSON, "json", false, "print JSON")
+	rootCmd.AddCommand(detectCmd)
+}
+
+// detectReport is what tb detect reports. Directories are only filled in
+// with --explain.
+type detectReport struct {
+	ConfigFiles   []string               `json:"config_files"`
+	ForcedContext string                 `json:"forced_context,omitempty"`
+	Context       string                 `json:"context"`
+	Reason        string                 `json:"reason"`
+	Candidates    []contextpkg.Candidate `json:"candidates"`
+	Directories   []contextpkg.DirTrace  `json:"directories,omitempty"`
+}
+
+// runDetect detects the context of the current directory and reports it
+func runDetect(out io.Writer) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+	pm := getPluginManager()
+	addPluginContexts(cfg, pm)
+
+	trace, err := newDetector(cfg, pm).Explain(".")
+	if err != nil {
+		return withExitCode(ExitContext, fmt.Errorf("failed to detect context: %w", err))
+	}
+	report := newDetectReport(cfg, trace, forceCtx)
+	if !explainDetection {
+		report.Directories = nil
+	}
+
+	switch {
+	case detectJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	case explainDetection:
+		printDetectReport(out, report)
+	case report.Context != "":
+		fmt.Fprintln(out, report.Context)
+	}
+
+	if report.Context == "" {
+		absDir, _ := filepath.Abs(".")
+		return withExitCode(ExitContext, fmt.Errorf("%w in %s or parent directories", contextpkg.ErrNoContext, absDir))
+	}
+	return nil
+}
+
+// newDetectReport builds the report for a detection trace: the forced
+// context wins, and otherwise the best ranked candidate
+func newDetectReport(cfg *config.Config, trace *contextpkg.Trace, forced string) *detectReport {
+	report := &detectReport{
+		ConfigFiles:   append([]string{}, cfg.Sources...),
+		ForcedContext: forced,
+		Candidates:    trace.Candidates,
+		Directories:   trace.Dirs,
+	}
+	if report.Candidates == nil {
+		report.Candidates = []contextpkg.Candidate{}
+	}
+
+	candidates := trace.Candidates
+	switch {
+	case forced != "":
+		report.Context = forced
+		report.Reason = "forced with --context"
+	case len(candidates) == 0:
+		report.Reason = "no context detected"
+	case len(candidates) == 1:
+		report.Context = candidates[0].Context
+		report.Reason = "only detected context"
+	default:
+		report.Context = candidates[0].Context
+		report.Reason = fmt.Sprintf("ranks before %s: %s", candidates[1].Context, contextpkg.RankReason(candidates[0], candidates[1]))
+	}
+	return report
+}
+
+// printDetectReport prints a detection report for people
+func printDetectReport(out io.Writer, report *detectReport) {
+	if len(report.ConfigFiles) == 0 {
+		fmt.Fprintln(out, "Config: built-in defaults")
+	}
+	for _, source := range report.ConfigFiles {
+		fmt.Fprintf(out, "Config file: %s\n", source)
+	}
+	if report.ForcedContext != "" {
+		fmt.Fprintf(out, "Context override: --context %s\n", report.ForcedContext)
+	} else {
+		fmt.Fprintln(out, "Context override: none")
+	}
+
+	for _, dir := range report.Directories {
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "Searched %s (%s):\n", dir.Dir, levelsUp(dir.Distance))
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, check := range dir.Sources {
+			result := "not found"
+			if check.Found {
+				result = "found " + check.Context
+			}
+			fmt.Fprintf(w, "  plugin\t%s\t%s\n", check.Plugin, result)
+		}
+		for _, check := range dir.Rules {
+			result := "not found"
+			if check.Found {
+				result = "found " + check.File
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", check.Context, check.Rule, result)
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(out)
+	if len(report.Candidates) == 0 {
+		fmt.Fprintln(out, "Candidates: none")
+	} else {
+		fmt.Fprintln(out, "Candidates:")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for i, c := range report.Candidates {
+			fmt.Fprintf(w, "  %d.\t%s\t%.2f\t%s\n", i+1, c.Context, c.Score, detectedFrom(c))
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(out)
+	if report.Context == "" {
+		fmt.Fprintf(out, "Decision: none (%s)\n", report.Reason)
+	} else {
+		fmt.Fprintf(out, "Decision: %s (%s)\n", report.Context, report.Reason)
+	}
+}
+
+// levelsUp describes how far above the starting directory a directory is
+func levelsUp(distance int) string {
+	switch distance {
+	case 0:
+		return "starting directory"
+	case 1:
+		return "1 level up"
+	}
+	return fmt.Sprintf("%d levels up", distance)
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// TestNewDetectReport tests the decision and its reason
+func TestNewDetectReport(t *testing.T) {
+	goCandidate := contextpkg.Candidate{Context: "go", Score: 1, Markers: []string{"go.mod"}}
+	makeCandidate := contextpkg.Candidate{Context: "make", Score: 0.5, Distance: 1, Markers: []string{"Makefile"}}
+
+	tests := []struct {
+		name       string
+		candidates []contextpkg.Candidate
+		forced     string
+		want       string
+		wantReason string
+	}{
+		{name: "none", wantReason: "no context detected"},
+		{name: "single", candidates: []contextpkg.Candidate{goCandidate}, want: "go", wantReason: "only detected context"},
+		{name: "ranked", candidates: []contextpkg.Candidate{goCandidate, makeCandidate}, want: "go", wantReason: "ranks before make: higher score"},
+		{name: "forced", candidates: []contextpkg.Candidate{goCandidate}, forced: "node", want: "node", wantReason: "forced with --context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newDetectReport(&config.Config{}, &contextpkg.Trace{Candidates: tt.candidates}, tt.forced)
+			if report.Context != tt.want || report.Reason != tt.wantReason {
+				t.Errorf("newDetectReport() = %q (%s), want %q (%s)", report.Context, report.Reason, tt.want, tt.wantReason)
+			}
+			if report.ConfigFiles == nil || report.Candidates == nil {
+				t.Error("newDetectReport() lists must not be nil, so that JSON has [] instead of null")
+			}
+		})
+	}
+}
+
+// TestRunDetect tests the plain, explained and JSON output
+func TestRunDetect(t *testing.T) {
+	defer func(explain, asJSON bool) { explainDetection, detectJSON = explain, asJSON }(explainDetection, detectJSON)
+	t.Setenv("HOME", t.TempDir())
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatalf("failed to create go.mod: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	var out bytes.Buffer
+	explainDetection, detectJSON = false, false
+	if err := runDetect(&out); err != nil {
+		t.Fatalf("runDetect() unexpected error: %v", err)
+	}
+	if out.String() != "go\n" {
+		t.Errorf("runDetect() = %q, want %q", out.String(), "go\n")
+	}
+
+	out.Reset()
+	explainDetection = true
+	if err := runDetect(&out); err != nil {
+		t.Fatalf("runDetect() unexpected error: %v", err)
+	}
+	for _, want := range []string{"Config: built-in defaults", "Context override: none", "Searched " + tmpDir, "found go.mod", "plugin  docker", "Decision: go"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runDetect() with --explain missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	detectJSON = true
+	if err := runDetect(&out); err != nil {
+		t.Fatalf("runDetect() unexpected error: %v", err)
+	}
+	var report detectReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("runDetect() printed invalid JSON: %v", err)
+	}
+	if report.Context != "go" || len(report.Directories) == 0 || len(report.Directories[0].Rules) == 0 {
+		t.Errorf("runDetect() JSON = %+v, want context go with checked rules", report)
+	}
+}
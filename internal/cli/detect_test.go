@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// TestResolutionChain tests grouping detected contexts into lookup tiers
+func TestResolutionChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []contextpkg.Candidate
+		want       [][]string
+	}{
+		{
+			name:       "single context",
+			candidates: []contextpkg.Candidate{{Context: "go", Score: 1}},
+			want:       [][]string{{"go"}},
+		},
+		{
+			name: "primary context stands alone even when tied",
+			candidates: []contextpkg.Candidate{
+				{Context: "docker", Score: 1, Plugin: "docker"},
+				{Context: "go", Score: 1},
+				{Context: "node", Score: 1},
+				{Context: "make", Score: 0.5, Distance: 1},
+			},
+			want: [][]string{{"docker"}, {"go", "node"}, {"make"}},
+		},
+		{
+			name: "priority and distance split tiers",
+			candidates: []contextpkg.Candidate{
+				{Context: "nextjs", Score: 1, Priority: 10},
+				{Context: "dotnet", Score: 1, Priority: 5},
+				{Context: "node", Score: 1},
+				{Context: "go", Score: 0.5},
+				{Context: "make", Score: 0.5, Distance: 1},
+			},
+			want: [][]string{{"nextjs"}, {"dotnet"}, {"node"}, {"go"}, {"make"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolutionChain(tt.candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolutionChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProjectDir tests which detected directory commands run in
+func TestProjectDir(t *testing.T) {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	root := filepath.Dir(wd)
+	candidates := []contextpkg.Candidate{
+		{Context: "go", Dir: root, Distance: 1},
+		{Context: "docker", Dir: wd, Plugin: "docker"},
+	}
+
+	tests := []struct {
+		name        string
+		candidates  []contextpkg.Candidate
+		ctx         string
+		detectedCtx string
+		want        string
+	}{
+		{name: "detected in a parent", candidates: candidates, ctx: "go", detectedCtx: "go", want: root},
+		{name: "detected here", candidates: candidates, ctx: "docker", detectedCtx: "go", want: ""},
+		{name: "global runs where the detected context is", candidates: candidates, ctx: "global", detectedCtx: "go", want: root},
+		{name: "forced context that is not detected", candidates: candidates, ctx: "node", detectedCtx: "node", want: ""},
+		{name: "no candidates", ctx: "go", detectedCtx: "go", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := projectDir(tt.candidates, tt.ctx, tt.detectedCtx); got != tt.want {
+				t.Errorf("projectDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// Exit codes for tb's own failures. A command that fails passes its own
+// exit code through; one killed by signal N exits with 128+N. tb's own
+// codes follow sysexits.h, above the small codes test runners and
+// compilers use, so a script can tell tb's failures from the command's.
+const (
+	// ExitFailure is used for errors without a more specific code
+	ExitFailure = 1
+
+	// ExitUsage means tb was called with invalid flags or arguments, as
+	// EX_USAGE in sysexits.h
+	ExitUsage = 64
+
+	// ExitContext means no context was detected or the context is unknown,
+	// as EX_NOINPUT
+	ExitContext = 66
+
+	// ExitUnknownCommand means the context does not define the command, as
+	// EX_UNAVAILABLE
+	ExitUnknownCommand = 69
+
+	// ExitConfig means the configuration could not be loaded, as EX_CONFIG
+	ExitConfig = 78
+
+	// ExitTimeout means the command ran out of time, as with timeout(1)
+	ExitTimeout = 124
+
+	// ExitNotFound means the command's program could not be found
+	ExitNotFound = 127
+
+	// exitSignal is added to the number of the signal that stopped a command
+	exitSignal = 128
+)
+
+// ExitError is an error that carries the exit code tb should exit with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode attaches an exit code to err
+func withExitCode(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode returns the exit code for an error returned by Execute: the code
+// attached to it, the exit code of the command that failed, 124 for a
+// timeout, 128+N for signal N, and 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ExitError
+	var timeoutErr *timeoutError
+	var interrupted *interruptError
+	var processErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.As(err, &timeoutErr):
+		return ExitTimeout
+	case errors.As(err, &interrupted):
+		if sig, ok := interrupted.signal.(syscall.Signal); ok {
+			return exitSignal + int(sig)
+		}
+	case errors.As(err, &processErr):
+		if code := processErr.ExitCode(); code >= 0 {
+			return code
+		}
+		if status, ok := processErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return exitSignal + int(status.Signal())
+		}
+	}
+	return ExitFailure
+}
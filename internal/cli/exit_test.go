@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// TestExitCode tests mapping errors to tb's exit code
+func TestExitCode(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	killedErr := exec.Command("sh", "-c", "kill -9 $$").Run()
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: 0},
+		{name: "plain error", err: errors.New("boom"), want: ExitFailure},
+		{name: "attached code", err: withExitCode(ExitConfig, errors.New("bad config")), want: ExitConfig},
+		{name: "wrapped attached code", err: fmt.Errorf("step 'a' failed: %w", withExitCode(ExitNotFound, errors.New("nope"))), want: ExitNotFound},
+		{name: "command exit code", err: fmt.Errorf("command failed: %w", exitErr), want: 3},
+		{name: "hook exit code", err: &hookError{phase: "before", owner: "build", err: exitErr}, want: 3},
+		{name: "killed by signal", err: fmt.Errorf("command failed: %w", killedErr), want: 128 + 9},
+		{name: "timeout", err: &timeoutError{limit: time.Second}, want: ExitTimeout},
+		{name: "interrupted", err: &interruptError{signal: syscall.SIGINT}, want: 130},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHandleDynamicCommand_ExitCodes tests the exit codes of tb's own failures
+func TestHandleDynamicCommand_ExitCodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		want   int
+	}{
+		{
+			name:   "command exit code",
+			config: "contexts:\n  go:\n    commands:\n      fail: sh -c 'exit 7'\n",
+			args:   []string{"fail"},
+			want:   7,
+		},
+		{
+			name: "unknown command",
+			args: []string{"nope"},
+			want: ExitUnknownCommand,
+		},
+		{
+			name: "unknown context",
+			args: []string{"--context", "nope", "build"},
+			want: ExitContext,
+		},
+		{
+			name: "invalid flag value",
+			args: []string{"--jobs", "0", "build"},
+			want: ExitUsage,
+		},
+		{
+			name:   "invalid configuration",
+			config: "contexts:\n  go:\n    commands:\n      test: go test -run 'oops\n",
+			args:   []string{"test"},
+			want:   ExitConfig,
+		},
+		{
+			name:   "template that does not expand",
+			config: "contexts:\n  go:\n    commands:\n      show: echo {{.Nope}}\n",
+			args:   []string{"show"},
+			want:   ExitConfig,
+		},
+		{
+			name:   "missing argument",
+			config: "contexts:\n  go:\n    commands:\n      show: echo {{arg 1}}\n",
+			args:   []string{"show"},
+			want:   ExitUsage,
+		},
+		{
+			name:   "program not found",
+			config: "contexts:\n  go:\n    commands:\n      missing: tb-no-such-program\n",
+			args:   []string{"missing"},
+			want:   ExitNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+				t.Fatalf("failed to write go.mod: %v", err)
+			}
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".toolbox.yaml"), []byte(tt.config), 0644); err != nil {
+					t.Fatalf("failed to write config: %v", err)
+				}
+			}
+
+			oldDir, _ := os.Getwd()
+			if err := os.Chdir(dir); err != nil {
+				t.Fatalf("failed to change directory: %v", err)
+			}
+			oldCfgFile, oldForceCtx, oldJobs := cfgFile, forceCtx, jobs
+			defer func() {
+				os.Chdir(oldDir)
+				cfgFile, forceCtx, jobs = oldCfgFile, oldForceCtx, oldJobs
+			}()
+
+			err := handleDynamicCommand(rootCmd, tt.args)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("handleDynamicCommand() exit code = %d (%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestShowHelp_ExitCodes tests that tb help fails with the codes running a
+// command would
+func TestShowHelp_ExitCodes(t *testing.T) {
+	defer func(file, ctx string) { cfgFile, forceCtx = file, ctx }(cfgFile, forceCtx)
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	badConfig := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(badConfig, []byte("contexts: [\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cfgFile string
+		context string
+		command string
+		want    int
+	}{
+		{name: "invalid configuration", cfgFile: badConfig, command: "build", want: ExitConfig},
+		{name: "unknown context", context: "nope", command: "build", want: ExitContext},
+		{name: "unknown command", command: "nope", want: ExitUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile, forceCtx = tt.cfgFile, tt.context
+			err := showHelp(&cobra.Command{}, []string{tt.command})
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("showHelp() exit code = %d (%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
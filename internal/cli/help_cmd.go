@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 
 	"github.com/bamf0/toolbox/internal/config"
-	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/bamf0/toolbox/internal/registry"
 	"github.com/spf13/cobra"
 )
 
@@ -39,67 +41,60 @@ func showHelp(cmd *cobra.Command, args []string) error {
 	}
 
 	commandName := args[0]
+	out := cmd.OutOrStdout()
 
 	// Load configuration
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load config: %w", err))
 	}
 
 	// Merge plugin contexts
 	pm := getPluginManager()
-	pluginContexts := pm.GetContexts()
-	for ctxName, ctxConfig := range pluginContexts {
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			cfg.Contexts[ctxName] = ctxConfig
-		}
-	}
-
-	// Detect or use forced context
-	var detectedCtx string
-	if forceCtx != "" {
-		detectedCtx = forceCtx
-	} else {
-		pluginCtx, _, foundByPlugin := pm.DetectContext(".")
-		if foundByPlugin {
-			detectedCtx = pluginCtx
-		} else {
-			detector := contextpkg.NewDetector()
-			detectedCtx, err = detector.Detect(".")
-			if err != nil {
-				// If we can't detect context, show all contexts where this command exists
-				return showCommandInAllContexts(commandName, cfg)
-			}
-		}
-	}
+	addPluginContexts(cfg, pm)
 
-	// Find the command in the detected context
-	ctxConfig, exists := cfg.Contexts[detectedCtx]
-	if !exists {
-		return fmt.Errorf("context '%s' not found", detectedCtx)
+	// Look the command up the way running it does
+	chain, _, err := lookupChain(cfg, pm)
+	if err != nil {
+		// If we can't detect context, show all contexts where this command exists
+		return showCommandInAllContexts(out, commandName, cfg)
 	}
+	detectedCtx := chain[0][0]
 
-	cmdString, cmdExists := ctxConfig.Commands[commandName]
-	if !cmdExists {
+	plan, err := registry.New(cfg).Resolve(chain, commandName)
+	switch {
+	case errors.Is(err, registry.ErrUnknownContext):
+		return withExitCode(ExitContext, fmt.Errorf("context '%s' not found", detectedCtx))
+	case errors.Is(err, registry.ErrUnknownCommand):
 		// Check if command exists in other contexts
-		return showCommandInAllContexts(commandName, cfg)
+		return showCommandInAllContexts(out, commandName, cfg)
+	case err != nil:
+		return err
 	}
+	ctxConfig := cfg.Contexts[plan.Context]
 
 	// Display help
-	fmt.Printf("Command: %s\n", commandName)
-	fmt.Printf("Context: %s\n\n", detectedCtx)
+	fmt.Fprintf(out, "Command: %s\n", commandName)
+	fmt.Fprintf(out, "Context: %s\n", plan.Context)
+	if plan.Context != detectedCtx {
+		fmt.Fprintf(out, "  (not defined in context '%s'; found in '%s')\n", detectedCtx, plan.Context)
+	}
+	if len(plan.Ambiguous) > 0 {
+		fmt.Fprintf(out, "  (also defined in %s; choose with --context)\n", registry.QuoteList(plan.Ambiguous))
+	}
+	fmt.Fprintln(out)
 
-	if description, hasDesc := ctxConfig.Descriptions[commandName]; hasDesc {
-		fmt.Printf("Description:\n  %s\n\n", description)
+	if description := ctxConfig.Description(commandName); description != "" {
+		fmt.Fprintf(out, "Description:\n  %s\n\n", description)
 	}
 
-	fmt.Printf("Executes:\n  %s\n", cmdString)
+	fmt.Fprintf(out, "Executes:\n  %s\n", ctxConfig.Commands[commandName])
 
 	return nil
 }
 
 // showCommandInAllContexts shows where a command exists across all contexts
-func showCommandInAllContexts(commandName string, cfg *config.Config) error {
+func showCommandInAllContexts(out io.Writer, commandName string, cfg *config.Config) error {
 	var foundContexts []string
 
 	for ctxName, ctxConfig := range cfg.Contexts {
@@ -109,24 +104,24 @@ func showCommandInAllContexts(commandName string, cfg *config.Config) error {
 	}
 
 	if len(foundContexts) == 0 {
-		return fmt.Errorf("command '%s' not found in any context", commandName)
+		return withExitCode(ExitUnknownCommand, fmt.Errorf("command '%s' not found in any context", commandName))
 	}
 
-	fmt.Printf("Command '%s' is available in the following contexts:\n\n", commandName)
+	fmt.Fprintf(out, "Command '%s' is available in the following contexts:\n\n", commandName)
 
 	sort.Strings(foundContexts)
 	for _, ctxName := range foundContexts {
 		ctxConfig := cfg.Contexts[ctxName]
 		cmdString := ctxConfig.Commands[commandName]
 
-		fmt.Printf("Context: %s\n", ctxName)
-		if desc, hasDesc := ctxConfig.Descriptions[commandName]; hasDesc {
-			fmt.Printf("  Description: %s\n", desc)
+		fmt.Fprintf(out, "Context: %s\n", ctxName)
+		if desc := ctxConfig.Description(commandName); desc != "" {
+			fmt.Fprintf(out, "  Description: %s\n", desc)
 		}
-		fmt.Printf("  Executes: %s\n\n", cmdString)
+		fmt.Fprintf(out, "  Executes: %s\n\n", cmdString)
 	}
 
-	fmt.Printf("Use 'tb --context <context> %s' to run in a specific context.\n", commandName)
+	fmt.Fprintf(out, "Use 'tb --context <context> %s' to run in a specific context.\n", commandName)
 
 	return nil
 }
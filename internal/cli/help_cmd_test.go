@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// TestShowHelp_ResolutionChain tests that tb help describes the command tb
+// would run: from the detected context, a lower ranked one or a forced one
+func TestShowHelp_ResolutionChain(t *testing.T) {
+	defer func(file, ctx string) { cfgFile, forceCtx = file, ctx }(cfgFile, forceCtx)
+	t.Setenv("HOME", t.TempDir())
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                 "module x\n",
+		"Makefile":               "release: ## Tag a release\n\tgit tag v1\n",
+		config.ProjectConfigName: "contexts:\n  node:\n    commands:\n      release: npm publish\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		command string
+		want    []string
+		notWant string
+	}{
+		{
+			name:    "detected context",
+			command: "test",
+			want:    []string{"Context: go\n", "Executes:\n  go test ./..."},
+			notWant: "available in the following contexts",
+		},
+		{
+			name:    "fallback context",
+			command: "release",
+			want:    []string{"Context: make\n", "not defined in context 'go'; found in 'make'", "Tag a release", "make release"},
+			notWant: "npm publish",
+		},
+		{
+			name:    "forced context",
+			context: "node",
+			command: "release",
+			want:    []string{"Context: node\n", "npm publish"},
+			notWant: "make release",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile, forceCtx = "", tt.context
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+
+			if err := showHelp(cmd, []string{tt.command}); err != nil {
+				t.Fatalf("showHelp() unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("showHelp() missing %q in:\n%s", want, out.String())
+				}
+			}
+			if strings.Contains(out.String(), tt.notWant) {
+				t.Errorf("showHelp() shows %q in:\n%s", tt.notWant, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// hookError reports a hook that failed, apart from the command it belongs to
+type hookError struct {
+	phase string
+	owner string
+	err   error
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("%s hook for '%s' failed: %v", e.phase, e.owner, e.err)
+}
+
+func (e *hookError) Unwrap() error {
+	return e.err
+}
+
+// runWithHooks runs the before hooks, then run, then the on_failure hooks if
+// a before hook or run failed, and finally the after hooks. A failing before
+// hook stops run from running. When run fails, that error is returned and
+// any hook failures are only reported; otherwise the first hook failure is
+// returned. With --no-hooks, only run runs.
+func runWithHooks(ctx context.Context, owner string, hooks config.Hooks, data *templateData, std streams, run func() error) error {
+	if noHooks || hooks.IsEmpty() {
+		return run()
+	}
+
+	var hookErrs []error
+	runHooks := func(phase string, list config.HookList) error {
+		for _, hook := range list {
+			if err := runCommand(ctx, hook, data, nil, std); err != nil {
+				hookErr := &hookError{phase: phase, owner: owner, err: err}
+				hookErrs = append(hookErrs, hookErr)
+				if phase == "before" {
+					return hookErr
+				}
+			}
+		}
+		return nil
+	}
+
+	err := runHooks("before", hooks.Before)
+	if err == nil {
+		err = run()
+	}
+	if err != nil {
+		runHooks("on_failure", hooks.OnFailure)
+	}
+	runHooks("after", hooks.After)
+
+	if err == nil && len(hookErrs) > 0 {
+		err = hookErrs[0]
+	}
+	for _, hookErr := range hookErrs {
+		if hookErr != err {
+			fmt.Fprintf(std.stderr, "Warning: %v\n", hookErr)
+		}
+	}
+	return err
+}
+
+// printHooks shows the hooks that run around a command, for --dry-run and
+// --verbose
+func printHooks(prefix, owner string, hooks config.Hooks) {
+	if noHooks || hooks.IsEmpty() {
+		return
+	}
+
+	fmt.Printf("%sHooks for %s:\n", prefix, owner)
+	lists := []struct {
+		phase string
+		hooks config.HookList
+	}{
+		{"before", hooks.Before},
+		{"on_failure", hooks.OnFailure},
+		{"after", hooks.After},
+	}
+	for _, list := range lists {
+		if len(list.hooks) == 0 {
+			continue
+		}
+		commands := make([]string, len(list.hooks))
+		for i, hook := range list.hooks {
+			commands[i] = hook.String()
+		}
+		fmt.Printf("%s  %s: %s\n", prefix, list.phase, strings.Join(commands, "; "))
+	}
+}
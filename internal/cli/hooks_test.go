@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// TestRunWithHooks tests hook order and how hook failures are reported
+func TestRunWithHooks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	errMain := errors.New("main failed")
+
+	tests := []struct {
+		name       string
+		hooks      config.Hooks
+		mainErr    error
+		noHooks    bool
+		wantLog    string
+		wantErr    string
+		wantStderr string
+	}{
+		{
+			name: "success runs before and after",
+			hooks: config.Hooks{
+				Before:    config.HookList{{Run: "echo before", Shell: "sh"}},
+				After:     config.HookList{{Run: "echo after", Shell: "sh"}},
+				OnFailure: config.HookList{{Run: "echo on_failure", Shell: "sh"}},
+			},
+			wantLog: "before,main,after",
+		},
+		{
+			name: "failure runs on_failure before after",
+			hooks: config.Hooks{
+				After:     config.HookList{{Run: "echo after", Shell: "sh"}},
+				OnFailure: config.HookList{{Run: "echo on_failure", Shell: "sh"}},
+			},
+			mainErr: errMain,
+			wantLog: "main,on_failure,after",
+			wantErr: "main failed",
+		},
+		{
+			name: "failing before hook stops the command",
+			hooks: config.Hooks{
+				Before:    config.HookList{{Run: "false"}},
+				After:     config.HookList{{Run: "echo after", Shell: "sh"}},
+				OnFailure: config.HookList{{Run: "echo on_failure", Shell: "sh"}},
+			},
+			wantLog: "on_failure,after",
+			wantErr: "before hook for 'build' failed",
+		},
+		{
+			name: "failing after hook fails a successful command",
+			hooks: config.Hooks{
+				After: config.HookList{{Run: "false"}, {Run: "echo after", Shell: "sh"}},
+			},
+			wantLog: "main,after",
+			wantErr: "after hook for 'build' failed",
+		},
+		{
+			name: "hook failures after a failed command are warnings",
+			hooks: config.Hooks{
+				OnFailure: config.HookList{{Run: "false"}},
+			},
+			mainErr:    errMain,
+			wantLog:    "main",
+			wantErr:    "main failed",
+			wantStderr: "Warning: on_failure hook for 'build' failed",
+		},
+		{
+			name: "no hooks",
+			hooks: config.Hooks{
+				Before: config.HookList{{Run: "echo before", Shell: "sh"}},
+			},
+			noHooks: true,
+			wantLog: "main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldNoHooks := noHooks
+			defer func() { noHooks = oldNoHooks }()
+			noHooks = tt.noHooks
+
+			log := filepath.Join(t.TempDir(), "log")
+			for _, list := range []config.HookList{tt.hooks.Before, tt.hooks.After, tt.hooks.OnFailure} {
+				for i := range list {
+					if list[i].Shell != "" {
+						list[i].Run += " >> " + log
+					}
+				}
+			}
+
+			var stdout, stderr bytes.Buffer
+			std := streams{stdout: &stdout, stderr: &stderr}
+			err := runWithHooks(context.Background(), "build", tt.hooks, nil, std, func() error {
+				f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					return err
+				}
+				f.WriteString("main\n")
+				f.Close()
+				return tt.mainErr
+			})
+
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("runWithHooks() unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("runWithHooks() error = %v, want error containing %q", err, tt.wantErr)
+			}
+			if tt.wantStderr != "" && !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantStderr)
+			}
+
+			got, _ := os.ReadFile(log)
+			if log := strings.Join(strings.Fields(string(got)), ","); log != tt.wantLog {
+				t.Errorf("ran %q, want %q", log, tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// streams connects a command to its input and output
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// standardStreams connects a command to tb's own stdin, stdout and stderr
+func standardStreams() streams {
+	return streams{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+}
+
+// prefixColors are the ANSI colors cycled through for step prefixes
+var prefixColors = []string{"36", "33", "35", "32", "34", "31"}
+
+// useColor reports whether output to f should be colored: only on a terminal,
+// and never when NO_COLOR is set (https://no-color.org)
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// stepPrefix returns the line prefix for step i, padded to width and colored
+// when color is set
+func stepPrefix(name string, i, width int, color bool) string {
+	prefix := fmt.Sprintf("%-*s | ", width, name)
+	if !color {
+		return prefix
+	}
+	return "\x1b[" + prefixColors[i%len(prefixColors)] + "m" + prefix + "\x1b[0m"
+}
+
+// prefixWriter writes complete lines to out, each starting with prefix.
+// Writers that share a mutex never interleave within a line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+// newPrefixWriter creates a writer that prefixes every line written to out
+func newPrefixWriter(mu *sync.Mutex, out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{mu: mu, out: out, prefix: prefix}
+}
+
+// Write buffers p and writes out every line it completes
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes out a final line that did not end in a newline
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := io.WriteString(w.out, w.prefix); err != nil {
+		return err
+	}
+	_, err := w.out.Write(line)
+	return err
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// TestPrefixWriter tests that every line is prefixed, including partial lines
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&mu, &buf, "lint | ")
+
+	writes := []string{"first line\nsec", "ond line\n", "\n", "no newline"}
+	for _, s := range writes {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+
+	want := "lint | first line\nlint | second line\nlint | \nlint | no newline\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// TestStepPrefix tests padding and coloring of step prefixes
+func TestStepPrefix(t *testing.T) {
+	if got := stepPrefix("go", 0, 5, false); got != "go    | " {
+		t.Errorf("stepPrefix() = %q, want %q", got, "go    | ")
+	}
+	if got := stepPrefix("go", 1, 2, true); got != "\x1b[33mgo | \x1b[0m" {
+		t.Errorf("stepPrefix() = %q, want colored prefix", got)
+	}
+}
@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bamf0/toolbox/internal/registry"
+)
+
+// stepResult records how a step of a plan ended
+type stepResult struct {
+	status   string
+	duration time.Duration
+	err      error
+}
+
+// runPlan runs the steps of a plan in dependency order, up to jobs steps at
+// a time. User arguments go to the requested command only. A failed step
+// cancels the running steps and stops the plan unless it continues on error;
+// steps that need a failed step never run.
+func runPlan(ctx context.Context, plan *registry.Plan, data *templateData, userArgs []string) error {
+	target := plan.Target()
+	if !plan.IsComposite() {
+		stepData := data.forContext(target.Context)
+		return runWithHooks(ctx, target.Name, target.Command.Hooks, stepData, standardStreams(), func() error {
+			return executeCommandSecure(ctx, target.Command, stepData, userArgs)
+		})
+	}
+
+	// A composite command's own hooks run around all of its steps
+	return runWithHooks(ctx, plan.Name, plan.Command.Hooks, data.forContext(plan.Context), standardStreams(), func() error {
+		return runSteps(ctx, plan, data, userArgs)
+	})
+}
+
+// runSteps runs the steps of a composite plan, each inside its own hooks
+func runSteps(ctx context.Context, plan *registry.Plan, data *templateData, userArgs []string) error {
+	target := plan.Target()
+
+	steps := plan.Steps
+	last := len(steps) - 1
+
+	// Ask every confirmation before anything runs, so prompts never
+	// interleave with step output
+	declined := make([]bool, len(steps))
+	for i, step := range steps {
+		if step.Command.Confirm && !(target != nil && i == last) && !confirmCommand(step.Name, step.Command) {
+			if !plan.ContinueOnError {
+				return fmt.Errorf("step '%s' cancelled", step.Name)
+			}
+			declined[i] = true
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := jobs
+	if limit < 1 {
+		limit = 1
+	}
+	parallel := limit > 1 && len(steps) > 1
+
+	width := 0
+	for _, step := range steps {
+		if len(step.Name) > width {
+			width = len(step.Name)
+		}
+	}
+	color := useColor(os.Stdout)
+	var outputMu sync.Mutex
+
+	// results is only touched by this goroutine; running steps report back
+	// through done
+	results := make([]*stepResult, len(steps))
+	type finished struct {
+		index  int
+		result *stepResult
+	}
+	done := make(chan finished)
+	running := 0
+	failedStep := -1
+
+	start := func(i int) {
+		step := steps[i]
+		var args []string
+		if target != nil && i == last {
+			args = userArgs
+		}
+
+		std := standardStreams()
+		var flush func()
+		if parallel {
+			prefix := stepPrefix(step.Name, i, width, color)
+			stdout := newPrefixWriter(&outputMu, os.Stdout, prefix)
+			stderr := newPrefixWriter(&outputMu, os.Stderr, prefix)
+			std = streams{stdout: stdout, stderr: stderr}
+			flush = func() {
+				stdout.Flush()
+				stderr.Flush()
+			}
+			fmt.Fprintf(stdout, "$ %s\n", step.Command)
+		} else {
+			fmt.Printf("==> %s: %s\n", step.Name, step.Command)
+		}
+
+		running++
+		go func() {
+			began := time.Now()
+			stepData := data.forContext(step.Context)
+			err := runWithHooks(ctx, step.Name, step.Command.Hooks, stepData, std, func() error {
+				return runCommand(ctx, step.Command, stepData, args, std)
+			})
+			if flush != nil {
+				flush()
+			}
+			done <- finished{index: i, result: &stepResult{err: err, duration: time.Since(began)}}
+		}()
+	}
+
+	for {
+		// Start every step that is ready, in plan order
+		for i := range steps {
+			if results[i] != nil || running >= limit || failedStep >= 0 {
+				continue
+			}
+			switch {
+			case declined[i]:
+				results[i] = &stepResult{status: "declined", err: fmt.Errorf("declined")}
+			case unmetNeed(plan, results, i) != "":
+				results[i] = &stepResult{status: "skipped"}
+			case needsDone(plan, results, i):
+				results[i] = &stepResult{status: "running"}
+				start(i)
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		f := <-done
+		running--
+		i, result := f.index, f.result
+		result.status = stepStatus(result.err)
+		results[i] = result
+
+		if result.err != nil {
+			var interrupted *interruptError
+			if failedStep >= 0 && ctx.Err() != nil {
+				result.status = "cancelled"
+			} else if !plan.ContinueOnError || errors.As(result.err, &interrupted) {
+				// Fail fast: stop the siblings that are still running. A
+				// signal sent to tb stops the plan even when it continues
+				// on error.
+				failedStep = i
+				cancel()
+			}
+		}
+	}
+
+	var failed []string
+	for i, result := range results {
+		if result == nil {
+			results[i] = &stepResult{status: "cancelled"}
+			continue
+		}
+		if result.err != nil && result.status != "cancelled" {
+			failed = append(failed, steps[i].Name)
+		}
+	}
+
+	printSummary(plan, results)
+
+	if failedStep >= 0 {
+		return fmt.Errorf("step '%s' failed: %w", steps[failedStep].Name, results[failedStep].err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d steps failed: %s", len(failed), len(steps), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// unmetNeed returns the name of a step that step i needs and that finished
+// without succeeding, or "" when there is none
+func unmetNeed(plan *registry.Plan, results []*stepResult, i int) string {
+	for _, need := range plan.Steps[i].Needs {
+		if r := results[need]; r != nil && r.status != "running" && r.status != "ok" {
+			return plan.Steps[need].Name
+		}
+	}
+	return ""
+}
+
+// needsDone reports whether every step that step i needs has succeeded
+func needsDone(plan *registry.Plan, results []*stepResult, i int) bool {
+	for _, need := range plan.Steps[i].Needs {
+		if r := results[need]; r == nil || r.status != "ok" {
+			return false
+		}
+	}
+	return true
+}
+
+// stepStatus describes how a step ended for the summary
+func stepStatus(err error) string {
+	var exitErr *exec.ExitError
+	var timeoutErr *timeoutError
+	var interrupted *interruptError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &timeoutErr):
+		return "timed out"
+	case errors.As(err, &interrupted):
+		return "interrupted"
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return fmt.Sprintf("exit %d", exitErr.ExitCode())
+	default:
+		return "failed"
+	}
+}
+
+// printSummary prints the status and duration of every step of a plan
+func printSummary(plan *registry.Plan, results []*stepResult) {
+	width := len("STEP")
+	for _, step := range plan.Steps {
+		if len(step.Name) > width {
+			width = len(step.Name)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%-*s  %-10s  %s\n", width, "STEP", "STATUS", "DURATION")
+	for i, step := range plan.Steps {
+		duration := "-"
+		if results[i].duration > 0 {
+			duration = results[i].duration.Round(time.Millisecond).String()
+		}
+		fmt.Printf("%-*s  %-10s  %s\n", width, step.Name, results[i].status, duration)
+	}
+}
+
+// printPlan shows what will run for a plan, for --dry-run and --verbose
+func printPlan(plan *registry.Plan, data *templateData, commandArgs []string) error {
+	printHooks("", "context "+plan.Context, plan.Hooks)
+
+	if !plan.IsComposite() {
+		target := plan.Target()
+		if err := printCommand(target.Command, data.forContext(target.Context), commandArgs); err != nil {
+			return err
+		}
+		printHooks("", target.Name, target.Command.Hooks)
+		return nil
+	}
+
+	mode := "stops at the first failure"
+	if plan.ContinueOnError {
+		mode = "continues after failures"
+	}
+	fmt.Printf("Plan: %s (%d steps, %s)\n", plan.Name, len(plan.Steps), mode)
+
+	for i, step := range plan.Steps {
+		line := fmt.Sprintf("  %d. %s: %s", i+1, step.Name, step.Command)
+		if step.Context != plan.Context {
+			line += fmt.Sprintf(" [%s]", step.Context)
+		}
+		if len(step.Needs) > 0 {
+			needs := make([]string, len(step.Needs))
+			for j, need := range step.Needs {
+				needs[j] = plan.Steps[need].Name
+			}
+			line += fmt.Sprintf(" (needs %s)", strings.Join(needs, ", "))
+		}
+		fmt.Println(line)
+		printHooks("     ", step.Name, step.Command.Hooks)
+	}
+	printHooks("", plan.Name, plan.Command.Hooks)
+
+	if target := plan.Target(); target != nil && len(commandArgs) > 0 {
+		fmt.Printf("Additional arguments for %s: %s\n", target.Name, strings.Join(commandArgs, " "))
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bamf0/toolbox/internal/config"
+	"github.com/bamf0/toolbox/internal/registry"
+)
+
+// TestRunPlan tests stopping and continuing after failed steps
+func TestRunPlan(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		errMsg          string
+		wantMarks       []string
+		wantNoMarks     []string
+	}{
+		{
+			name:        "stops at the first failure",
+			errMsg:      "step 'fail' failed",
+			wantMarks:   []string{"first"},
+			wantNoMarks: []string{"after", "dependent"},
+		},
+		{
+			name:            "continue on error skips dependents only",
+			continueOnError: true,
+			errMsg:          "1 of 4 steps failed: fail",
+			wantMarks:       []string{"first", "after"},
+			wantNoMarks:     []string{"dependent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			mark := func(name string) config.Command {
+				return config.Command{Run: "touch " + filepath.Join(tmpDir, name)}
+			}
+
+			dependent := mark("dependent")
+			dependent.Needs = []string{"fail"}
+
+			cfg := &config.Config{
+				Contexts: map[string]config.ContextConfig{
+					"test": {
+						Commands: map[string]config.Command{
+							"first":     mark("first"),
+							"fail":      {Run: "false"},
+							"after":     mark("after"),
+							"dependent": dependent,
+							"ci": {
+								Steps:           []string{"first", "fail", "after", "dependent"},
+								ContinueOnError: tt.continueOnError,
+							},
+						},
+					},
+				},
+			}
+
+			plan, err := registry.New(cfg).GetCommand("test", "ci")
+			if err != nil {
+				t.Fatalf("GetCommand() unexpected error: %v", err)
+			}
+
+			err = runPlan(context.Background(), plan, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("runPlan() error = %v, want error containing %q", err, tt.errMsg)
+			}
+
+			for _, name := range tt.wantMarks {
+				if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+					t.Errorf("step %q did not run", name)
+				}
+			}
+			for _, name := range tt.wantNoMarks {
+				if _, err := os.Stat(filepath.Join(tmpDir, name)); err == nil {
+					t.Errorf("step %q ran, want it skipped", name)
+				}
+			}
+		})
+	}
+}
+
+// TestRunPlan_ArgsGoToTarget tests that user arguments reach only the requested command
+func TestRunPlan_ArgsGoToTarget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	cfg := &config.Config{
+		Contexts: map[string]config.ContextConfig{
+			"test": {
+				Commands: map[string]config.Command{
+					"setup": {Run: "test $# -eq 0", Shell: "sh"},
+					"check": {Run: "test", Needs: []string{"setup"}},
+				},
+			},
+		},
+	}
+
+	plan, err := registry.New(cfg).GetCommand("test", "check")
+	if err != nil {
+		t.Fatalf("GetCommand() unexpected error: %v", err)
+	}
+
+	if err := runPlan(context.Background(), plan, nil, []string{"a", "=", "a"}); err != nil {
+		t.Errorf("runPlan() unexpected error: %v", err)
+	}
+}
+
+// TestRunPlan_Parallel tests running independent steps concurrently
+func TestRunPlan_Parallel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	oldJobs := jobs
+	defer func() { jobs = oldJobs }()
+	jobs = 2
+
+	t.Run("independent steps overlap", func(t *testing.T) {
+		cfg := &config.Config{
+			Contexts: map[string]config.ContextConfig{
+				"test": {
+					Commands: map[string]config.Command{
+						"lint": {Run: "sleep 0.5"},
+						"test": {Run: "sleep 0.5"},
+						"ci":   {Steps: []string{"lint", "test"}},
+					},
+				},
+			},
+		}
+		plan, err := registry.New(cfg).GetCommand("test", "ci")
+		if err != nil {
+			t.Fatalf("GetCommand() unexpected error: %v", err)
+		}
+
+		began := time.Now()
+		if err := runPlan(context.Background(), plan, nil, nil); err != nil {
+			t.Fatalf("runPlan() unexpected error: %v", err)
+		}
+		if elapsed := time.Since(began); elapsed > 900*time.Millisecond {
+			t.Errorf("runPlan() took %v, want steps to run concurrently", elapsed)
+		}
+	})
+
+	t.Run("fail fast cancels siblings", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Contexts: map[string]config.ContextConfig{
+				"test": {
+					Commands: map[string]config.Command{
+						"slow":  {Run: "sleep 5"},
+						"fail":  {Run: "sh -c 'sleep 0.2; exit 3'"},
+						"later": {Run: "touch " + filepath.Join(tmpDir, "later"), Needs: []string{"slow"}},
+						"ci":    {Steps: []string{"slow", "fail", "later"}},
+					},
+				},
+			},
+		}
+		plan, err := registry.New(cfg).GetCommand("test", "ci")
+		if err != nil {
+			t.Fatalf("GetCommand() unexpected error: %v", err)
+		}
+
+		began := time.Now()
+		err = runPlan(context.Background(), plan, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "step 'fail' failed") {
+			t.Errorf("runPlan() error = %v, want step 'fail' failed", err)
+		}
+		if elapsed := time.Since(began); elapsed > 3*time.Second {
+			t.Errorf("runPlan() took %v, want the slow sibling cancelled", elapsed)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "later")); err == nil {
+			t.Error("step 'later' ran after the plan failed")
+		}
+	})
+}
+
+// TestStepStatus tests the statuses shown in the summary
+func TestStepStatus(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "success", err: nil, want: "ok"},
+		{name: "exit code", err: fmt.Errorf("command failed: %w", exitErr), want: "exit 3"},
+		{name: "timeout", err: &timeoutError{limit: time.Second}, want: "timed out"},
+		{name: "other error", err: fmt.Errorf("command not found: nope"), want: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stepStatus(tt.err); got != tt.want {
+				t.Errorf("stepStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
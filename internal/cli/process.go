@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// interruptError reports a command that tb stopped because tb itself
+// received a signal
+type interruptError struct {
+	signal os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return fmt.Sprintf("command stopped by signal: %v", e.signal)
+}
+
+// runProcess starts cmd in its own process group and waits for it. Signals
+// tb receives in the meantime are forwarded to the whole group. When ctx
+// ends, the group gets SIGTERM, and whatever is still running after the
+// grace period is killed.
+func runProcess(ctx context.Context, cmd *exec.Cmd) error {
+	tty := setProcessGroup(cmd)
+
+	// Catch signals before the child exists, so none is missed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if tty != nil {
+		defer restoreForeground(tty)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var received os.Signal
+	for {
+		select {
+		case err := <-done:
+			// Children left behind by an interrupted command, such as
+			// background jobs of a shell, are stopped with it
+			if received != nil || (cmd.ProcessState != nil && cmd.ProcessState.ExitCode() == -1) {
+				terminateProcessGroup(cmd)
+			}
+			if received != nil {
+				return &interruptError{signal: received}
+			}
+			return err
+
+		case sig := <-signals:
+			received = sig
+			signalProcessGroup(cmd, sig)
+
+		case <-ctx.Done():
+			terminateProcessGroup(cmd)
+			select {
+			case <-done:
+			case <-time.After(gracePeriod):
+				killProcessGroup(cmd)
+				<-done
+			}
+			// The command was stopped, even if it exited cleanly
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals are caught while a command runs. The console delivers
+// Ctrl-C to the command itself, so tb only has to keep running until the
+// command exits.
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// setProcessGroup leaves the command in tb's console group; there is no
+// terminal to hand over
+func setProcessGroup(cmd *exec.Cmd) *os.File {
+	return nil
+}
+
+// restoreForeground is not needed without process groups
+func restoreForeground(tty *os.File) {}
+
+// signalProcessGroup does nothing: the command received the same console
+// event as tb
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) {}
+
+// terminateProcessGroup stops the command; there is no graceful
+// termination signal to send
+func terminateProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// forwardedSignals are passed on to the running command instead of
+// stopping tb
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// setProcessGroup makes cmd the leader of a new process group, so that
+// signals reach everything it starts. When tb owns the terminal and the
+// command reads from it, the new group becomes the terminal's foreground
+// group; the terminal is returned so that tb can take it back.
+func setProcessGroup(cmd *exec.Cmd) *os.File {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	tty, ok := cmd.Stdin.(*os.File)
+	if !ok || !isForeground(tty) {
+		return nil
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(tty.Fd())
+	return tty
+}
+
+// isForeground reports whether f is a terminal whose foreground process
+// group is tb's own
+func isForeground(f *os.File) bool {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	return errno == 0 && int(pgrp) == syscall.Getpgrp()
+}
+
+// restoreForeground makes tb's process group the terminal's foreground
+// group again
+func restoreForeground(tty *os.File) {
+	// tb is in the background at this point, so the kernel would stop it
+	// with SIGTTOU for changing the foreground group
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	pgrp := int32(syscall.Getpgrp())
+	syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+}
+
+// signalProcessGroup sends sig to every process in cmd's group
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		syscall.Kill(-cmd.Process.Pid, s)
+	}
+}
+
+// terminateProcessGroup asks every process in cmd's group to exit
+func terminateProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcessGroup kills every process in cmd's group
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,133 @@
+//go:build unix
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// processGone reports whether pid has exited. Exited processes may linger
+// as zombies when nothing reaps them, which counts as gone.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	return err == nil && strings.Contains(string(stat), ") Z ")
+}
+
+// waitForFile waits up to five seconds for path to exist
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+	}
+	t.Fatalf("%s was not created", path)
+}
+
+// TestRunCommand_TimeoutStopsProcessGroup tests that a timeout reaches the
+// children of a command, first with SIGTERM and then with SIGKILL
+func TestRunCommand_TimeoutStopsProcessGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	oldGrace := gracePeriod
+	defer func() { gracePeriod = oldGrace }()
+
+	tests := []struct {
+		name     string
+		script   string
+		grace    time.Duration
+		wantMark bool
+	}{
+		{
+			name:     "children get SIGTERM",
+			script:   `trap 'echo stopped > "$1"; exit 0' TERM; sleep 30 & echo $! > "$2"; wait`,
+			grace:    5 * time.Second,
+			wantMark: true,
+		},
+		{
+			name:   "ignored SIGTERM ends in SIGKILL",
+			script: `trap '' TERM; sleep 30 & echo $! > "$2"; while :; do sleep 0.1; done`,
+			grace:  200 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gracePeriod = tt.grace
+			tmpDir := t.TempDir()
+			mark := filepath.Join(tmpDir, "mark")
+			pidFile := filepath.Join(tmpDir, "pid")
+
+			timeout := 500 * time.Millisecond
+			command := config.Command{Run: tt.script, Shell: "sh", Timeout: &timeout}
+
+			began := time.Now()
+			err := executeCommandSecure(context.Background(), command, nil, []string{mark, pidFile})
+			var timeoutErr *timeoutError
+			if !errors.As(err, &timeoutErr) {
+				t.Fatalf("executeCommandSecure() error = %v, want timeout error", err)
+			}
+			if elapsed := time.Since(began); elapsed > 3*time.Second {
+				t.Errorf("executeCommandSecure() took %v, want the grace period respected", elapsed)
+			}
+
+			if _, err := os.Stat(mark); (err == nil) != tt.wantMark {
+				t.Errorf("SIGTERM trap ran = %v, want %v", err == nil, tt.wantMark)
+			}
+
+			data, err := os.ReadFile(pidFile)
+			if err != nil {
+				t.Fatalf("failed to read child pid: %v", err)
+			}
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+			for deadline := time.Now().Add(2 * time.Second); !processGone(pid); time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					syscall.Kill(pid, syscall.SIGKILL)
+					t.Fatalf("child process %d outlived the command", pid)
+				}
+			}
+		})
+	}
+}
+
+// TestRunCommand_ForwardsSignals tests that signals sent to tb reach the command
+func TestRunCommand_ForwardsSignals(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	ready := filepath.Join(tmpDir, "ready")
+	mark := filepath.Join(tmpDir, "mark")
+
+	go func() {
+		waitForFile(t, ready)
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	}()
+
+	command := config.Command{Run: `trap 'echo hup > "$2"; exit 0' HUP; sleep 30 & touch "$1"; wait`, Shell: "sh"}
+	err := executeCommandSecure(context.Background(), command, nil, []string{ready, mark})
+
+	var interrupted *interruptError
+	if !errors.As(err, &interrupted) || interrupted.signal != syscall.SIGHUP {
+		t.Errorf("executeCommandSecure() error = %v, want interrupted by SIGHUP", err)
+	}
+	if _, err := os.Stat(mark); err != nil {
+		t.Error("command did not receive the forwarded SIGHUP")
+	}
+}
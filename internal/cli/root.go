@@ -1,17 +1,22 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bamf0/toolbox/internal/config"
-	contextpkg "github.com/bamf0/toolbox/internal/context"
 	"github.com/bamf0/toolbox/internal/registry"
+	"github.com/bamf0/toolbox/internal/shellwords"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +24,10 @@ const (
 	// DefaultCommandTimeout is the maximum time a command can run
 	DefaultCommandTimeout = 10 * time.Minute
 
+	// DefaultGracePeriod is how long a command may take to exit after
+	// SIGTERM before it is killed
+	DefaultGracePeriod = 5 * time.Second
+
 	// MaxArgumentLength limits individual argument size to prevent memory exhaustion
 	MaxArgumentLength = 8192
 
@@ -43,8 +52,32 @@ var (
 	verbose        bool
 	versionFlag    bool
 	commandTimeout time.Duration
+	gracePeriod    time.Duration
+
+	// jobs limits how many steps of a composite command run at once
+	jobs int
+
+	// noHooks skips before, after and on_failure hooks
+	noHooks bool
+
+	// startDir is the directory given with -C, which tb changes to before
+	// anything else
+	startDir string
+
+	// allProjects runs the command in every project of the workspace
+	allProjects bool
+
+	// onlyProjects limits --all to projects whose paths match these patterns
+	onlyProjects []string
+
+	// timeoutFlagSet records whether --timeout was given explicitly, in which
+	// case it takes precedence over per-command timeouts
+	timeoutFlagSet bool
 )
 
+// confirmInput is where answers to confirmation prompts are read from
+var confirmInput io.Reader = os.Stdin
+
 var rootCmd = &cobra.Command{
 	Use:   "tb",
 	Short: "ToolBox - Context-aware command aliasing",
@@ -85,13 +118,19 @@ func customHelp(cmd *cobra.Command, args []string) {
 	
 	// Show flags
 	fmt.Println("Flags:")
-	fmt.Println("      --config string      config file (default: .toolbox.yaml or ~/.toolbox/config.yaml)")
-	fmt.Println("      --context string     force a specific context (node, go, python, etc.)")
-	fmt.Println("      --dry-run            print command without executing")
-	fmt.Println("  -h, --help               help for tb")
-	fmt.Println("      --timeout duration   command execution timeout (default 10m0s)")
-	fmt.Println("      --verbose            verbose output")
-	fmt.Println("      --version            show version information")
+	fmt.Println("  -C, --directory dir           run as if tb was started in dir")
+	fmt.Println("      --all                     run the command in every project of the workspace")
+	fmt.Println("      --config string           config file (default: .toolbox.yaml or ~/.toolbox/config.yaml)")
+	fmt.Println("      --context string          force a specific context (node, go, python, etc.)")
+	fmt.Println("      --dry-run                 print command without executing")
+	fmt.Println("      --grace-period duration   time a stopped command gets to exit before it is killed (default 5s)")
+	fmt.Println("  -h, --help                    help for tb")
+	fmt.Println("      --jobs int                run up to N independent steps of a composite command, or projects with --all, at once (default 1)")
+	fmt.Println("      --no-hooks                skip before, after and on_failure hooks")
+	fmt.Println("      --only pattern            with --all, only run in projects matching pattern (repeatable)")
+	fmt.Println("      --timeout duration        command execution timeout (default 10m0s)")
+	fmt.Println("      --verbose                 verbose output")
+	fmt.Println("      --version                 show version information")
 	fmt.Println()
 	fmt.Println("Use \"tb [command] --help\" for more information about a command.")
 	fmt.Println("Use \"tb status\" to see current context and available commands.")
@@ -107,28 +146,18 @@ func showContextCommands() {
 
 	// Merge plugin contexts
 	pm := getPluginManager()
-	pluginContexts := pm.GetContexts()
-	for ctxName, ctxConfig := range pluginContexts {
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			cfg.Contexts[ctxName] = ctxConfig
-		}
-	}
+	addPluginContexts(cfg, pm)
 
 	// Detect context
 	var activeContext string
 	if forceCtx != "" {
 		activeContext = forceCtx
 	} else {
-		pluginCtx, _, foundByPlugin := pm.DetectContext(".")
-		if foundByPlugin {
-			activeContext = pluginCtx
-		} else {
-			detector := contextpkg.NewDetector()
-			activeContext, err = detector.Detect(".")
-			if err != nil {
-				return // No context detected
-			}
+		best, err := detectContext(cfg, pm)
+		if err != nil {
+			return // No context detected
 		}
+		activeContext = best.Context
 	}
 
 	// Get commands for the active context
@@ -151,7 +180,7 @@ func showContextCommands() {
 	contextConfig, exists := cfg.Contexts[activeContext]
 	if exists {
 		for _, cmdName := range commands {
-			desc := contextConfig.Descriptions[cmdName]
+			desc := contextConfig.Description(cmdName)
 			if desc != "" {
 				fmt.Printf("  %-12s %s\n", cmdName, desc)
 			} else {
@@ -169,56 +198,21 @@ func showContextCommands() {
 // Execute runs the root command and returns any error encountered.
 // This is the main entry point for the CLI application.
 func Execute() error {
-	// Pre-process args to handle special cases
-	args := os.Args[1:]
-	
 	// Handle -- separator (everything after -- is passed to the command)
-	separatorIdx := -1
-	for i, arg := range args {
-		if arg == "--" {
-			separatorIdx = i
-			break
-		}
-	}
-	
-	if separatorIdx >= 0 {
-		// Split args at --
-		// Before: tb flags and command
-		// After: arguments for the underlying command
-		beforeSep := args[:separatorIdx]
-		afterSep := args[separatorIdx+1:]
-		
-		// Find the command name (first non-flag arg before --)
-		var cmdName string
-		tbFlags := []string{}
-		for _, arg := range beforeSep {
-			if !strings.HasPrefix(arg, "-") && cmdName == "" {
-				cmdName = arg
-			} else {
-				tbFlags = append(tbFlags, arg)
-			}
-		}
-		
-		if cmdName != "" {
-			// Reconstruct: tb [flags] command -- [command args]
-			// becomes: tb [flags] command [command args]
-			os.Args = append([]string{os.Args[0]}, tbFlags...)
-			os.Args = append(os.Args, cmdName)
-			os.Args = append(os.Args, afterSep...)
-		}
-	}
+	os.Args = append(os.Args[:1], joinSeparatedArgs(os.Args[1:])...)
 	
 	// Pre-process args to handle --help on dynamic commands
-	args = os.Args[1:]
-	if len(args) >= 2 {
+	args := os.Args[1:]
+	if len(args) >= 2 && commandIndex(args) == 0 {
 		// Check if this looks like a dynamic command with --help
-		// (not a known subcommand like "plugin", "completion", "help", "status")
+		// (not a known subcommand like "plugin", "completion", "help", "status", "detect")
 		potentialCmd := args[0]
 		knownCommands := map[string]bool{
 			"plugin":     true,
 			"completion": true,
 			"help":       true,
 			"status":     true,
+			"detect":     true,
 		}
 
 		if !knownCommands[potentialCmd] {
@@ -236,6 +230,66 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// valueFlags are the global flags that take the next argument as their
+// value. The --flag=value form is a single argument and needs no entry.
+var valueFlags = map[string]bool{
+	"--config":       true,
+	"--context":      true,
+	"--timeout":      true,
+	"--grace-period": true,
+	"--jobs":         true,
+	"-C":             true,
+	"--directory":    true,
+	"--only":         true,
+}
+
+// commandIndex returns the index of the command name in tb's arguments: the
+// first argument that is neither a flag nor the value of one. It is -1 when
+// there is no command.
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case valueFlags[arg]:
+			i++ // skip the value
+		case !strings.HasPrefix(arg, "-"):
+			return i
+		}
+	}
+	return -1
+}
+
+// joinSeparatedArgs rewrites tb [flags] command -- [command args] as
+// tb [flags] command [command args], so that the arguments after -- are
+// passed to the command even when they look like tb flags
+func joinSeparatedArgs(args []string) []string {
+	separatorIdx := -1
+	for i, arg := range args {
+		if arg == "--" {
+			separatorIdx = i
+			break
+		}
+	}
+	if separatorIdx < 0 {
+		return args
+	}
+
+	// Before: tb flags and command
+	// After: arguments for the underlying command
+	beforeSep := args[:separatorIdx]
+	afterSep := args[separatorIdx+1:]
+
+	cmdIdx := commandIndex(beforeSep)
+	if cmdIdx < 0 {
+		return args
+	}
+
+	joined := make([]string, 0, len(args)-1)
+	joined = append(joined, beforeSep[:cmdIdx]...)
+	joined = append(joined, beforeSep[cmdIdx+1:]...)
+	joined = append(joined, beforeSep[cmdIdx])
+	return append(joined, afterSep...)
+}
+
 // GetVersion returns the current version of ToolBox
 func GetVersion() string {
 	return Version
@@ -247,8 +301,25 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print command without executing")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", DefaultCommandTimeout, "command execution timeout")
+	rootCmd.PersistentFlags().DurationVar(&gracePeriod, "grace-period", DefaultGracePeriod, "time a stopped command gets to exit before it is killed")
+	rootCmd.PersistentFlags().IntVar(&jobs, "jobs", 1, "run up to N independent steps of a composite command, or projects with --all, at once")
+	rootCmd.PersistentFlags().BoolVar(&noHooks, "no-hooks", false, "skip before, after and on_failure hooks")
+	rootCmd.PersistentFlags().StringVarP(&startDir, "directory", "C", "", "run as if tb was started in dir")
+	rootCmd.PersistentFlags().BoolVar(&allProjects, "all", false, "run the command in every project of the workspace")
+	rootCmd.PersistentFlags().StringArrayVar(&onlyProjects, "only", nil, "with --all, only run in projects matching pattern (repeatable)")
 	rootCmd.Flags().BoolVar(&versionFlag, "version", false, "show version information")
 
+	// Subcommands parse their flags themselves; the root command changes
+	// directory once it has parsed its own
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return changeDir()
+	}
+
+	// Flag errors of subcommands are usage errors
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitUsage, err)
+	})
+
 	// Set custom help function
 	rootCmd.SetHelpFunc(customHelp)
 	
@@ -328,8 +399,57 @@ func handleDynamicCommand(cmd *cobra.Command, args []string) error {
 			var err error
 			commandTimeout, err = time.ParseDuration(args[i+1])
 			if err != nil {
-				return fmt.Errorf("invalid timeout duration: %w", err)
+				return withExitCode(ExitUsage, fmt.Errorf("invalid timeout duration: %w", err))
+			}
+			timeoutFlagSet = true
+			i++ // skip next arg
+			continue
+		}
+
+		// Handle --grace-period
+		if arg == "--grace-period" && i+1 < len(args) {
+			var err error
+			gracePeriod, err = time.ParseDuration(args[i+1])
+			if err != nil || gracePeriod < 0 {
+				return withExitCode(ExitUsage, fmt.Errorf("invalid --grace-period value %q", args[i+1]))
+			}
+			i++ // skip next arg
+			continue
+		}
+
+		// Handle --jobs
+		if arg == "--jobs" && i+1 < len(args) {
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return withExitCode(ExitUsage, fmt.Errorf("invalid --jobs value %q: must be a positive number", args[i+1]))
 			}
+			jobs = n
+			i++ // skip next arg
+			continue
+		}
+
+		// Handle --no-hooks
+		if arg == "--no-hooks" {
+			noHooks = true
+			continue
+		}
+
+		// Handle -C and --directory
+		if (arg == "-C" || arg == "--directory") && i+1 < len(args) {
+			startDir = args[i+1]
+			i++ // skip next arg
+			continue
+		}
+
+		// Handle --all
+		if arg == "--all" {
+			allProjects = true
+			continue
+		}
+
+		// Handle --only (repeatable)
+		if arg == "--only" && i+1 < len(args) {
+			onlyProjects = append(onlyProjects, args[i+1])
 			i++ // skip next arg
 			continue
 		}
@@ -346,6 +466,10 @@ func handleDynamicCommand(cmd *cobra.Command, args []string) error {
 		foundCommand = true
 	}
 
+	if err := changeDir(); err != nil {
+		return err
+	}
+
 	if commandName == "" {
 		return cmd.Help()
 	}
@@ -359,77 +483,195 @@ func handleDynamicCommand(cmd *cobra.Command, args []string) error {
 
 	// Validate arguments early
 	if err := validateArguments(commandArgs); err != nil {
-		return fmt.Errorf("invalid arguments: %w", err)
+		return withExitCode(ExitUsage, fmt.Errorf("invalid arguments: %w", err))
+	}
+
+	// Run the command in every project of the workspace instead
+	if len(onlyProjects) > 0 && !allProjects {
+		return withExitCode(ExitUsage, errors.New("--only requires --all"))
+	}
+	if allProjects {
+		return runWorkspace(commandName, commandArgs)
 	}
 
 	// Load configuration
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+	if verbose {
+		printConfigSources(cfg)
 	}
 
 	// Merge plugin contexts into config
 	pm := getPluginManager()
-	pluginContexts := pm.GetContexts()
-	for ctxName, ctxConfig := range pluginContexts {
-		// Only add if not already in config (config takes precedence)
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			cfg.Contexts[ctxName] = ctxConfig
-		}
-	}
+	addPluginContexts(cfg, pm)
 
-	// Detect context (or use forced context)
-	var detectedCtx string
-	if forceCtx != "" {
-		detectedCtx = forceCtx
-		if verbose {
+	// Detect context (or use forced context). The command is looked up in
+	// the detected contexts in rank order, then in the global context.
+	chain, candidates, err := lookupChain(cfg, pm)
+	if err != nil {
+		return withExitCode(ExitContext, fmt.Errorf("failed to detect context: %w", err))
+	}
+	detectedCtx := chain[0][0]
+	if verbose {
+		if forceCtx != "" {
 			fmt.Printf("Using forced context: %s\n", detectedCtx)
-		}
-	} else {
-		// Try plugin-based detection first
-		pluginCtx, pluginName, foundByPlugin := pm.DetectContext(".")
-
-		if foundByPlugin {
-			detectedCtx = pluginCtx
-			if verbose {
-				fmt.Printf("Detected context: %s (via plugin: %s)\n", detectedCtx, pluginName)
-			}
 		} else {
-			// Fall back to built-in detection
-			detector := contextpkg.NewDetector()
-			detectedCtx, err = detector.Detect(".")
-			if err != nil {
-				return fmt.Errorf("failed to detect context: %w", err)
-			}
-			if verbose {
-				fmt.Printf("Detected context: %s\n", detectedCtx)
-			}
+			fmt.Printf("Detected context: %s (from %s)\n", detectedCtx, detectedFrom(candidates[0]))
 		}
 	}
 
 	// Get command from registry
 	reg := registry.New(cfg)
-	baseCommand, err := reg.GetCommand(detectedCtx, commandName)
+	plan, err := reg.Resolve(chain, commandName)
 	if err != nil {
-		return fmt.Errorf("command '%s' not found in context '%s': %w", commandName, detectedCtx, err)
+		switch {
+		case errors.Is(err, registry.ErrUnknownContext):
+			return withExitCode(ExitContext, fmt.Errorf("command '%s' not found: %w", commandName, err))
+		case errors.Is(err, registry.ErrUnknownCommand):
+			return withExitCode(ExitUnknownCommand, fmt.Errorf("command '%s' not found: %w", commandName, err))
+		}
+		return err
 	}
+	if len(plan.Ambiguous) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: '%s' is not defined in context '%s' and is ambiguous among %s; using '%s' (choose with --context)\n",
+			commandName, detectedCtx, registry.QuoteList(append([]string{plan.Context}, plan.Ambiguous...)), plan.Context)
+	}
+	if plan.Command.IsComposite() && len(commandArgs) > 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("composite command '%s' does not accept arguments", commandName))
+	}
+
+	// Template values: the project, git state, environment and plugin variables
+	data := newTemplateData(context.Background(), ".", plan.Context)
+	data.Vars = pm.Variables(data.ProjectRoot)
+	data.workDir = projectDir(candidates, plan.Context, detectedCtx)
 
 	if dryRun || verbose {
-		fmt.Printf("Context: %s\n", detectedCtx)
-		fmt.Printf("Base command: %s\n", baseCommand)
-		if len(commandArgs) > 0 {
-			fmt.Printf("Additional arguments: %s\n", strings.Join(commandArgs, " "))
+		fmt.Printf("Context: %s\n", plan.Context)
+		if plan.Context != detectedCtx {
+			fmt.Printf("Command '%s' is not defined in context '%s'; using it from '%s'\n",
+				commandName, detectedCtx, plan.Context)
+		}
+		if err := printPlan(plan, data, commandArgs); err != nil {
+			return err
 		}
 		if dryRun {
 			return nil
 		}
 	}
 
-	// Execute the command securely
-	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
-	defer cancel()
+	if plan.Command.Confirm && !confirmCommand(commandName, plan.Command) {
+		return fmt.Errorf("command '%s' cancelled", commandName)
+	}
+
+	// Execute the command, or each step of the plan, securely, inside the
+	// context's hooks
+	return runWithHooks(context.Background(), plan.Context, plan.Hooks, data, standardStreams(), func() error {
+		return runPlan(context.Background(), plan, data, commandArgs)
+	})
+}
+
+// changeDir changes to the directory given with -C. It only does so once,
+// as a relative directory would otherwise be applied twice.
+func changeDir() error {
+	if startDir == "" {
+		return nil
+	}
+	if err := os.Chdir(startDir); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid -C directory: %w", err))
+	}
+	startDir = ""
+	return nil
+}
+
+// printCommand shows what will run: the command as configured, its
+// expansion and settings, and the arguments passed to it
+func printCommand(command config.Command, data *templateData, commandArgs []string) error {
+	if command.Shell != "" {
+		script, positional, expanded, err := expandScript(command, data, commandArgs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Shell: %s\n", shellInvocation(command.Shell))
+		fmt.Printf("Script:\n%s\n", indent(script))
+		printCommandSettings(expanded, data)
+		if len(positional) > 0 {
+			fmt.Printf("Positional arguments: %s\n", shellwords.Join(positional))
+		}
+		return nil
+	}
+
+	fmt.Printf("Base command: %s\n", command)
+	if strings.Contains(command.String(), "{{") {
+		// The expansion already has the arguments in place
+		argv, _, err := expandCommand(command, data, commandArgs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Expanded command: %s\n", shellwords.Join(argv))
+		printCommandSettings(command, data)
+		return nil
+	}
+	printCommandSettings(command, data)
+	if len(commandArgs) > 0 {
+		fmt.Printf("Additional arguments: %s\n", strings.Join(commandArgs, " "))
+	}
+	return nil
+}
+
+// indent prefixes every line of text with two spaces
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printCommandSettings shows the per-command settings that affect execution
+func printCommandSettings(command config.Command, data *templateData) {
+	if dir := commandDir(command, data); dir != "" {
+		fmt.Printf("Working directory: %s\n", relativeDir(dir))
+	}
+	for _, env := range command.EnvList() {
+		fmt.Printf("Environment: %s\n", env)
+	}
+	if timeout := commandTimeoutFor(command); timeout > 0 {
+		fmt.Printf("Timeout: %v\n", timeout)
+	} else {
+		fmt.Println("Timeout: none")
+	}
+}
+
+// commandTimeoutFor returns how long a command may run: an explicit --timeout
+// wins, then the command's own timeout, then the default. Zero means no limit.
+func commandTimeoutFor(command config.Command) time.Duration {
+	if !timeoutFlagSet && command.Timeout != nil {
+		return *command.Timeout
+	}
+	return commandTimeout
+}
+
+// confirmCommand asks whether to run a command marked with confirm: true
+func confirmCommand(commandName string, command config.Command) bool {
+	fmt.Fprintf(os.Stderr, "Run '%s' (%s)? [y/N] ", commandName, command)
 
-	return executeCommandSecure(ctx, baseCommand, commandArgs)
+	answer, _ := bufio.NewReader(confirmInput).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// printConfigSources reports which config files were merged, lowest priority first
+func printConfigSources(cfg *config.Config) {
+	if len(cfg.Sources) == 0 {
+		fmt.Println("Config: built-in defaults")
+		return
+	}
+	for _, source := range cfg.Sources {
+		fmt.Printf("Config file: %s\n", source)
+	}
 }
 
 // validateArguments performs security validation on user-supplied arguments
@@ -464,86 +706,105 @@ func containsDangerousPatterns(s string) bool {
 	return false
 }
 
-// executeCommandSecure runs the command WITHOUT shell interpretation
+// timeoutError reports a command killed because it ran out of time
+type timeoutError struct {
+	limit time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("command timed out after %v", e.limit)
+}
+
+// executeCommandSecure runs the command WITHOUT shell interpretation, unless
+// the command explicitly sets shell: in its definition
 // This is the primary defense against command injection
-func executeCommandSecure(ctx context.Context, baseCommand string, userArgs []string) error {
-	// Parse the base command into program and arguments
-	// We split on whitespace, which handles simple cases like "npm run build"
-	// For complex commands with pipes/redirects, those should be in shell scripts
-	parts := strings.Fields(baseCommand)
+// Templates in the command are expanded with data after splitting, one word
+// at a time; a nil data leaves them untouched. User arguments are never expanded.
+func executeCommandSecure(ctx context.Context, command config.Command, data *templateData, userArgs []string) error {
+	return runCommand(ctx, command, data, userArgs, standardStreams())
+}
+
+// runCommand is executeCommandSecure with the command connected to std, so
+// that steps running in parallel can have their output prefixed
+func runCommand(ctx context.Context, command config.Command, data *templateData, userArgs []string, std streams) error {
+	var parts []string
+	if command.Shell != "" {
+		// Shell commands: the script is one argument, user arguments are
+		// positional parameters or shell-quoted where placeholders put them
+		script, positional, expanded, err := expandScript(command, data, userArgs)
+		if err != nil {
+			return err
+		}
+		argv, cleanup, err := shellArgv(command.Shell, script, positional)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		parts, command = argv, expanded
+	} else {
+		// Parse the base command into program and arguments
+		// Words are split with shell quoting rules but nothing is expanded
+		// For pipes/redirects, set shell: on the command
+		argv, expanded, err := expandCommand(command, data, userArgs)
+		if err != nil {
+			return err
+		}
+		parts, command = argv, expanded
+	}
 	if len(parts) == 0 {
 		return fmt.Errorf("empty command")
 	}
 
 	program := parts[0]
-	baseArgs := parts[1:]
+	allArgs := parts[1:]
 
-	// Combine base arguments with user-supplied arguments
-	allArgs := append(baseArgs, userArgs...)
+	// A relative program path like ./run.sh is relative to the command's dir
+	dir := commandDir(command, data)
+	if dir != "" && strings.ContainsRune(program, filepath.Separator) && !filepath.IsAbs(program) {
+		if absProgram, err := filepath.Abs(filepath.Join(dir, program)); err == nil {
+			program = absProgram
+		}
+	}
 
 	// Validate that the program exists and is executable
 	programPath, err := exec.LookPath(program)
 	if err != nil {
-		return fmt.Errorf("command not found: %s: %w", program, err)
+		return withExitCode(ExitNotFound, fmt.Errorf("command not found: %s: %w", program, err))
 	}
 
 	if verbose {
-		fmt.Printf("Executing: %s %s\n", programPath, strings.Join(allArgs, " "))
+		fmt.Fprintf(std.stdout, "Executing: %s %s\n", programPath, strings.Join(allArgs, " "))
 	}
 
-	// Create command with explicit arguments (NO SHELL)
-	cmd := exec.CommandContext(ctx, programPath, allArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ() // Explicitly set environment
-
-	// Execute and handle errors with context
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("command timed out after %v", commandTimeout)
-		}
-		// Preserve original error for debugging
-		return fmt.Errorf("command failed: %w", err)
+	// Apply the command's timeout on top of the caller's context
+	if timeout := commandTimeoutFor(command); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
-
-	return nil
-}
-
-// executeCommandShellFallback is for commands that genuinely need shell features
-// This should ONLY be used for trusted commands from config files, NEVER user input
-// DEPRECATED: Use shell scripts in config instead
-func executeCommandShellFallback(ctx context.Context, command string) error {
-	// Determine shell based on OS
-	shell := "/bin/sh"
-	shellArg := "-c"
-
-	// Try to use bash if available (better error handling)
-	if bashPath, err := exec.LookPath("bash"); err == nil {
-		shell = bashPath
-	}
-
-	// On Windows, use cmd
-	if os.PathSeparator == '\\' {
-		shell = "cmd"
-		shellArg = "/C"
-	}
-
-	if verbose {
-		fmt.Printf("Warning: Using shell execution: %s %s %q\n", shell, shellArg, command)
+	var limit time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = time.Until(deadline).Round(time.Millisecond)
 	}
 
-	cmd := exec.CommandContext(ctx, shell, shellArg, command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ()
+	// Create command with explicit arguments (no shell unless opted in)
+	cmd := exec.Command(programPath, allArgs...)
+	cmd.Stdout = std.stdout
+	cmd.Stderr = std.stderr
+	cmd.Stdin = std.stdin
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), command.EnvList()...) // Explicitly set environment
 
-	if err := cmd.Run(); err != nil {
+	// Execute in its own process group and handle errors with context
+	if err := runProcess(ctx, cmd); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("command timed out after %v", commandTimeout)
+			return &timeoutError{limit: limit}
+		}
+		if _, ok := err.(*interruptError); ok {
+			return err
 		}
-		return fmt.Errorf("shell command failed: %w", err)
+		// Preserve original error for debugging
+		return fmt.Errorf("command failed: %w", err)
 	}
 
 	return nil
@@ -2,11 +2,15 @@ package cli
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/bamf0/toolbox/internal/config"
 )
 
 // TestValidateArguments tests argument validation security controls
@@ -157,7 +161,7 @@ func TestExecuteCommandSecure_NoShellInjection(t *testing.T) {
 			defer cancel()
 
 			// Execute command - errors are expected for some cases
-			_ = executeCommandSecure(ctx, tt.baseCommand, tt.userArgs)
+			_ = executeCommandSecure(ctx, config.Command{Run: tt.baseCommand}, nil, tt.userArgs)
 
 			// Check if canary file was created (it shouldn't be)
 			_, err := os.Stat(canaryFile)
@@ -210,7 +214,7 @@ func TestExecuteCommandSecure_ValidCommands(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			err := executeCommandSecure(ctx, tt.baseCommand, tt.userArgs)
+			err := executeCommandSecure(ctx, config.Command{Run: tt.baseCommand}, nil, tt.userArgs)
 
 			if tt.wantErr {
 				if err == nil {
@@ -236,7 +240,7 @@ func TestExecuteCommandSecure_Timeout(t *testing.T) {
 	defer cancel()
 
 	// sleep command should timeout
-	err := executeCommandSecure(ctx, "sleep", []string{"10"})
+	err := executeCommandSecure(ctx, config.Command{Run: "sleep"}, nil, []string{"10"})
 
 	if err == nil {
 		t.Error("executeCommandSecure() expected timeout error, got nil")
@@ -247,6 +251,135 @@ func TestExecuteCommandSecure_Timeout(t *testing.T) {
 	}
 }
 
+// TestExecuteCommandSecure_CommandSettings tests per-command env, dir, args and timeout
+func TestExecuteCommandSecure_CommandSettings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "marker.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to create marker file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tmpDir, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	shortTimeout := 100 * time.Millisecond
+	inProject := &templateData{workDir: tmpDir}
+
+	tests := []struct {
+		name    string
+		command config.Command
+		data    *templateData
+		errMsg  string
+	}{
+		{
+			name:    "env is set for the command",
+			command: config.Command{Run: "printenv TB_ALIAS_ONLY", Env: map[string]string{"TB_ALIAS_ONLY": "1"}},
+		},
+		{
+			name:    "env is not leaked into other commands",
+			command: config.Command{Run: "printenv TB_ALIAS_ONLY"},
+			errMsg:  "command failed",
+		},
+		{
+			name:    "dir sets the working directory",
+			command: config.Command{Run: "ls marker.txt", Dir: tmpDir},
+		},
+		{
+			name:    "commands run in the project root",
+			command: config.Command{Run: "ls marker.txt"},
+			data:    inProject,
+		},
+		{
+			name:    "dir is relative to the project root",
+			command: config.Command{Run: "ls ../marker.txt", Dir: "sub"},
+			data:    inProject,
+		},
+		{
+			name:    "dir cwd keeps the working directory",
+			command: config.Command{Run: "ls marker.txt", Dir: config.WorkingDir},
+			data:    inProject,
+			errMsg:  "command failed",
+		},
+		{
+			name:    "args are passed as single arguments",
+			command: config.Command{Run: "test", Args: []string{"two words", "=", "two words"}},
+		},
+		{
+			name:    "command timeout overrides the default",
+			command: config.Command{Run: "sleep 10", Timeout: &shortTimeout},
+			errMsg:  "timed out after 100ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommandSecure(context.Background(), tt.command, tt.data, nil)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("executeCommandSecure() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("executeCommandSecure() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestCommandTimeoutFor tests timeout precedence
+func TestCommandTimeoutFor(t *testing.T) {
+	defer func(d time.Duration, set bool) { commandTimeout, timeoutFlagSet = d, set }(commandTimeout, timeoutFlagSet)
+
+	none := time.Duration(0)
+	hour := time.Hour
+	commandTimeout = DefaultCommandTimeout
+	timeoutFlagSet = false
+
+	if got := commandTimeoutFor(config.Command{Run: "go test"}); got != DefaultCommandTimeout {
+		t.Errorf("default timeout = %v, want %v", got, DefaultCommandTimeout)
+	}
+	if got := commandTimeoutFor(config.Command{Run: "npm run dev", Timeout: &none}); got != 0 {
+		t.Errorf("disabled timeout = %v, want 0", got)
+	}
+	if got := commandTimeoutFor(config.Command{Run: "go test", Timeout: &hour}); got != hour {
+		t.Errorf("command timeout = %v, want %v", got, hour)
+	}
+
+	commandTimeout = time.Minute
+	timeoutFlagSet = true
+	if got := commandTimeoutFor(config.Command{Run: "go test", Timeout: &hour}); got != time.Minute {
+		t.Errorf("explicit --timeout = %v, want it to win over the command timeout", got)
+	}
+}
+
+// TestConfirmCommand tests the confirmation prompt answers
+func TestConfirmCommand(t *testing.T) {
+	defer func(r io.Reader) { confirmInput = r }(confirmInput)
+
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.answer), func(t *testing.T) {
+			confirmInput = strings.NewReader(tt.answer)
+			if got := confirmCommand("publish", config.Command{Run: "npm publish"}); got != tt.want {
+				t.Errorf("confirmCommand() with %q = %v, want %v", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestExecuteCommandSecure_MultiWordBaseCommand tests parsing of multi-word base commands
 func TestExecuteCommandSecure_MultiWordBaseCommand(t *testing.T) {
 	if testing.Short() {
@@ -278,7 +411,7 @@ func TestExecuteCommandSecure_MultiWordBaseCommand(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			err := executeCommandSecure(ctx, tt.baseCommand, tt.userArgs)
+			err := executeCommandSecure(ctx, config.Command{Run: tt.baseCommand}, nil, tt.userArgs)
 
 			if tt.wantErr && err == nil {
 				t.Errorf("executeCommandSecure() expected error, got nil")
@@ -356,7 +489,7 @@ func TestExecuteCommandSecure_RealWorldScenarios(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			err := executeCommandSecure(ctx, tt.baseCommand, tt.userArgs)
+			err := executeCommandSecure(ctx, config.Command{Run: tt.baseCommand}, nil, tt.userArgs)
 			if err != nil {
 				// Some commands may fail, but they shouldn't crash or allow injection
 				t.Logf("%s: command execution result: %v", tt.description, err)
@@ -382,7 +515,7 @@ func TestExecuteCommandSecure_EnvironmentIsolation(t *testing.T) {
 
 	// This test verifies that environment is passed correctly
 	// (In a real scenario, you might want to control this more strictly)
-	err := executeCommandSecure(ctx, "echo", []string{"test"})
+	err := executeCommandSecure(ctx, config.Command{Run: "echo"}, nil, []string{"test"})
 	if err != nil {
 		t.Errorf("executeCommandSecure() failed: %v", err)
 	}
@@ -393,7 +526,7 @@ func TestCommandNotFound(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := executeCommandSecure(ctx, "this-command-absolutely-does-not-exist-anywhere", []string{})
+	err := executeCommandSecure(ctx, config.Command{Run: "this-command-absolutely-does-not-exist-anywhere"}, nil, []string{})
 	if err == nil {
 		t.Error("expected error for nonexistent command, got nil")
 	}
@@ -408,7 +541,7 @@ func TestEmptyCommand(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := executeCommandSecure(ctx, "", []string{})
+	err := executeCommandSecure(ctx, config.Command{Run: ""}, nil, []string{})
 	if err == nil {
 		t.Error("expected error for empty command, got nil")
 	}
@@ -417,3 +550,57 @@ func TestEmptyCommand(t *testing.T) {
 		t.Errorf("expected 'empty' error, got: %v", err)
 	}
 }
+
+// TestJoinSeparatedArgs tests that the arguments after -- go to the command,
+// also when flags before it take a value
+func TestJoinSeparatedArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "no separator",
+			args: []string{"test", "-v"},
+			want: []string{"test", "-v"},
+		},
+		{
+			name: "command and arguments",
+			args: []string{"--dry-run", "test", "--", "-run", "TestFoo"},
+			want: []string{"--dry-run", "test", "-run", "TestFoo"},
+		},
+		{
+			name: "directory",
+			args: []string{"-C", "sub", "pwd", "--", "-L"},
+			want: []string{"-C", "sub", "pwd", "-L"},
+		},
+		{
+			name: "timeout",
+			args: []string{"--timeout", "5s", "test", "--", "-x"},
+			want: []string{"--timeout", "5s", "test", "-x"},
+		},
+		{
+			name: "flag with an equals value",
+			args: []string{"--context=go", "test", "--", "--help"},
+			want: []string{"--context=go", "test", "--help"},
+		},
+		{
+			name: "every value flag",
+			args: []string{"--config", "c.yaml", "--context", "go", "--jobs", "2", "--grace-period", "1s", "--directory", "d", "--only", "api", "--all", "build", "--", "x"},
+			want: []string{"--config", "c.yaml", "--context", "go", "--jobs", "2", "--grace-period", "1s", "--directory", "d", "--only", "api", "--all", "build", "x"},
+		},
+		{
+			name: "no command",
+			args: []string{"-C", "sub", "--", "x"},
+			want: []string{"-C", "sub", "--", "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinSeparatedArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("joinSeparatedArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// shellArgv returns the argv that runs script through shell with args as the
+// script's positional parameters. The arguments are passed separately from
+// the script text, so they are never parsed by the shell. The returned
+// cleanup function must be called once the command has finished.
+func shellArgv(shell, script string, args []string) ([]string, func(), error) {
+	switch shell {
+	case "bash", "sh":
+		// "tb" becomes $0, args become "$@"
+		argv := append([]string{shell, "-c", script, "tb"}, args...)
+		return argv, func() {}, nil
+
+	case "pwsh":
+		// pwsh only passes arguments to a script as $args when it runs a
+		// file; with -Command they would be appended to the script text
+		file, err := os.CreateTemp("", "tb-*.ps1")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create script file: %w", err)
+		}
+		cleanup := func() { os.Remove(file.Name()) }
+
+		if _, err := file.WriteString(script); err != nil {
+			file.Close()
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write script file: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write script file: %w", err)
+		}
+
+		argv := append([]string{"pwsh", "-NoProfile", "-NonInteractive", "-File", file.Name()}, args...)
+		return argv, cleanup, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported shell %q", shell)
+	}
+}
+
+// shellInvocation describes how a shell command is started, for --dry-run
+func shellInvocation(shell string) string {
+	program := shell
+	if path, err := exec.LookPath(shell); err == nil {
+		program = path
+	}
+
+	switch shell {
+	case "pwsh":
+		return program + " -NoProfile -NonInteractive -File <script>.ps1"
+	default:
+		return program + " -c <script> tb"
+	}
+}
+
+// shellQuoter returns the function that quotes a value as a single literal
+// word for shell
+func shellQuoter(shell string) func(string) string {
+	if shell == "pwsh" {
+		return quotePwsh
+	}
+	return quotePOSIX
+}
+
+// quotePOSIX quotes s for bash and sh using single quotes
+func quotePOSIX(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// pwshQuotes doubles every character PowerShell accepts as a single quote,
+// including the typographic variants
+var pwshQuotes = strings.NewReplacer(
+	"'", "''",
+	"\u2018", "\u2018\u2018",
+	"\u2019", "\u2019\u2019",
+	"\u201a", "\u201a\u201a",
+	"\u201b", "\u201b\u201b",
+)
+
+// quotePwsh quotes s for PowerShell using a verbatim string
+func quotePwsh(s string) string {
+	return "'" + pwshQuotes.Replace(s) + "'"
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// TestShellQuoter tests quoting values as single shell words
+func TestShellQuoter(t *testing.T) {
+	tests := []struct {
+		shell string
+		value string
+		want  string
+	}{
+		{shell: "bash", value: "plain", want: "'plain'"},
+		{shell: "sh", value: "two words", want: "'two words'"},
+		{shell: "bash", value: "it's; rm -rf /", want: `'it'\''s; rm -rf /'`},
+		{shell: "bash", value: "$(id)", want: "'$(id)'"},
+		{shell: "pwsh", value: "it's", want: "'it''s'"},
+		{shell: "pwsh", value: "a’b", want: "'a’’b'"},
+		{shell: "pwsh", value: "$env:PATH", want: "'$env:PATH'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell+" "+tt.value, func(t *testing.T) {
+			if got := shellQuoter(tt.shell)(tt.value); got != tt.want {
+				t.Errorf("shellQuoter(%q)(%q) = %s, want %s", tt.shell, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestShellArgv tests the argv built for each shell
+func TestShellArgv(t *testing.T) {
+	argv, cleanup, err := shellArgv("bash", "echo \"$@\"", []string{"a b", "; id"})
+	if err != nil {
+		t.Fatalf("shellArgv() unexpected error: %v", err)
+	}
+	cleanup()
+	want := []string{"bash", "-c", "echo \"$@\"", "tb", "a b", "; id"}
+	if !reflect.DeepEqual(argv, want) {
+		t.Errorf("shellArgv() = %q, want %q", argv, want)
+	}
+
+	argv, cleanup, err = shellArgv("pwsh", "Write-Output $args", []string{"x"})
+	if err != nil {
+		t.Fatalf("shellArgv() unexpected error: %v", err)
+	}
+	if len(argv) != 6 || argv[4] == "" || argv[5] != "x" {
+		t.Fatalf("shellArgv() = %q, want pwsh -File <script> x", argv)
+	}
+	script, err := os.ReadFile(argv[4])
+	if err != nil {
+		t.Fatalf("script file not written: %v", err)
+	}
+	if string(script) != "Write-Output $args" {
+		t.Errorf("script file = %q, want %q", script, "Write-Output $args")
+	}
+	cleanup()
+	if _, err := os.Stat(argv[4]); !os.IsNotExist(err) {
+		t.Errorf("cleanup() did not remove the script file")
+	}
+
+	if _, _, err := shellArgv("zsh", "true", nil); err == nil {
+		t.Error("shellArgv() expected error for unsupported shell, got nil")
+	}
+}
+
+// TestExecuteCommandSecure_Shell tests running commands through a shell
+func TestExecuteCommandSecure_Shell(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	pwned := filepath.Join(tmpDir, "pwned")
+	data := &templateData{
+		ProjectName: "x'; touch " + pwned + "; echo '",
+		Env:         map[string]string{},
+		Vars:        map[string]string{},
+	}
+
+	tests := []struct {
+		name     string
+		command  config.Command
+		userArgs []string
+		wantErr  bool
+	}{
+		{
+			name:    "pipes and && work",
+			command: config.Command{Run: "echo hello | grep -q hello && test -d .", Shell: "sh"},
+		},
+		{
+			name:    "failing script",
+			command: config.Command{Run: "exit 3", Shell: "sh"},
+			wantErr: true,
+		},
+		{
+			name:     "user args are positional parameters",
+			command:  config.Command{Run: `test "$#" -eq 2 && test "$1" = "a b"`, Shell: "bash"},
+			userArgs: []string{"a b", "c"},
+		},
+		{
+			name:     "user args are never parsed as script",
+			command:  config.Command{Run: `echo "$@"`, Shell: "bash"},
+			userArgs: []string{"; touch " + pwned},
+		},
+		{
+			name:     "fixed args come before user args",
+			command:  config.Command{Run: `test "$1" = fixed && test "$2" = user`, Shell: "sh", Args: []string{"fixed"}},
+			userArgs: []string{"user"},
+		},
+		{
+			name:    "template values are quoted in the script",
+			command: config.Command{Run: "echo {{.ProjectName}}", Shell: "sh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommandSecure(context.Background(), tt.command, data, tt.userArgs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("executeCommandSecure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+		t.Error("an argument or template value was executed by the shell")
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/bamf0/toolbox/internal/config"
 	contextpkg "github.com/bamf0/toolbox/internal/context"
-	"github.com/bamf0/toolbox/internal/plugin"
 	"github.com/bamf0/toolbox/internal/registry"
 	"github.com/spf13/cobra"
 )
@@ -15,10 +14,8 @@ var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show current context and available commands",
 	Long:  "Display the detected project context, available commands for that context, and other detected contexts.",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := showStatus(); err != nil {
-			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showStatus()
 	},
 }
 
@@ -30,45 +27,43 @@ func showStatus() error {
 	// Load configuration
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load config: %w", err))
 	}
 
 	// Merge plugin contexts into config
 	pm := getPluginManager()
-	pluginContexts := pm.GetContexts()
-	for ctxName, ctxConfig := range pluginContexts {
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			cfg.Contexts[ctxName] = ctxConfig
-		}
-	}
+	addPluginContexts(cfg, pm)
 
-	// Detect all contexts
-	var detectedContexts []string
+	// Rank every context found here; the best one is active
+	var candidates []contextpkg.Candidate
 	var activeContext string
 
 	if forceCtx != "" {
+		if _, exists := cfg.Contexts[forceCtx]; !exists {
+			return withExitCode(ExitContext, fmt.Errorf("%w '%s'", registry.ErrUnknownContext, forceCtx))
+		}
 		activeContext = forceCtx
 		fmt.Printf("Context: %s (forced)\n", activeContext)
 	} else {
-		// Try plugin-based detection first
-		pluginCtx, pluginName, foundByPlugin := pm.DetectContext(".")
-
-		if foundByPlugin {
-			activeContext = pluginCtx
-			fmt.Printf("Context: %s (detected via plugin: %s)\n", activeContext, pluginName)
+		candidates, err = newDetector(cfg, pm).DetectAll(".")
+		if err != nil {
+			return withExitCode(ExitContext, fmt.Errorf("failed to detect context: %w", err))
+		}
+		if len(candidates) == 0 {
+			fmt.Println("Context: none detected")
 		} else {
-			// Fall back to built-in detection
-			detector := contextpkg.NewDetector()
-			activeContext, err = detector.Detect(".")
-			if err != nil {
-				fmt.Println("Context: none detected")
-			} else {
-				fmt.Printf("Context: %s (detected)\n", activeContext)
-			}
+			activeContext = candidates[0].Context
+			fmt.Printf("Context: %s (detected from %s)\n", activeContext, detectedFrom(candidates[0]))
 		}
+	}
 
-		// Detect all possible contexts for "Other detected contexts"
-		detectedContexts = detectAllContexts(pm)
+	// Show the Go version a Go module requires
+	if activeContext == "go" || hasCandidate(candidates, "go") {
+		if mod, found := config.FindGoModule("."); found {
+			for _, line := range goModuleLines(mod) {
+				fmt.Println(line)
+			}
+		}
 	}
 
 	fmt.Println()
@@ -78,8 +73,9 @@ func showStatus() error {
 		reg := registry.New(cfg)
 		commands, err := reg.ListCommands(activeContext)
 		if err != nil {
-			fmt.Printf("Error listing commands: %v\n", err)
-		} else if len(commands) > 0 {
+			return withExitCode(ExitContext, err)
+		}
+		if len(commands) > 0 {
 			fmt.Printf("Available commands in '%s' context:\n", activeContext)
 			
 			// Sort commands alphabetically
@@ -89,7 +85,7 @@ func showStatus() error {
 			contextConfig, exists := cfg.Contexts[activeContext]
 			if exists {
 				for _, cmdName := range commands {
-					desc := contextConfig.Descriptions[cmdName]
+					desc := contextConfig.Description(cmdName)
 					cmd := contextConfig.Commands[cmdName]
 					
 					if desc != "" {
@@ -110,63 +106,39 @@ func showStatus() error {
 		}
 	}
 
-	// Show other detected contexts
-	if len(detectedContexts) > 1 {
+	// Show other detected contexts, best first
+	if len(candidates) > 1 {
 		fmt.Println()
 		fmt.Println("Other detected contexts:")
-		for _, ctx := range detectedContexts {
-			if ctx != activeContext {
-				fmt.Printf("  %s\n", ctx)
-			}
+		for _, c := range candidates[1:] {
+			fmt.Printf("  %-15s %.2f  %s\n", c.Context, c.Score, detectedFrom(c))
 		}
 	}
 
-	// Show configuration file being used
-	if cfgFile != "" {
-		fmt.Println()
-		fmt.Printf("Config file: %s\n", cfgFile)
-	}
+	// Show configuration files being used
+	fmt.Println()
+	printConfigSources(cfg)
 
 	return nil
 }
 
-// detectAllContexts returns all contexts that could be detected in the current directory
-func detectAllContexts(pm *plugin.PluginManager) []string {
-	var contexts []string
-	seen := make(map[string]bool)
-
-	// Check plugin contexts
-	pluginContexts := pm.DetectAllContexts(".")
-	for _, ctx := range pluginContexts {
-		if !seen[ctx] {
-			contexts = append(contexts, ctx)
-			seen[ctx] = true
+// goModuleLines describes a Go module for tb status: its path and the Go
+// version it requires, and the workspace it is part of
+func goModuleLines(mod *config.GoModule) []string {
+	var lines []string
+	if mod.Dir != "" {
+		line := "Go module: " + mod.Path
+		if req := mod.Requirement(); req != "" {
+			line += ", requires " + req
 		}
+		lines = append(lines, line)
 	}
-
-	// Check built-in contexts
-	detector := contextpkg.NewDetector()
-	builtinMarkers := map[string][]string{
-		"node":   {"package.json"},
-		"go":     {"go.mod"},
-		"python": {"pyproject.toml", "requirements.txt", "setup.py"},
-		"rust":   {"Cargo.toml"},
-		"make":   {"Makefile"},
-	}
-
-	for ctx, markers := range builtinMarkers {
-		if seen[ctx] {
-			continue
-		}
-		for _, marker := range markers {
-			if detector.FileExists(marker) {
-				contexts = append(contexts, ctx)
-				seen[ctx] = true
-				break
-			}
+	if mod.Work != "" {
+		label := "modules"
+		if len(mod.Use) == 1 {
+			label = "module"
 		}
+		lines = append(lines, fmt.Sprintf("Go workspace: %s (%d %s)", relativeDir(mod.Work), len(mod.Use), label))
 	}
-
-	sort.Strings(contexts)
-	return contexts
+	return lines
 }
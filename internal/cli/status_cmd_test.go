@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// TestGoModuleLines tests how tb status describes a Go module
+func TestGoModuleLines(t *testing.T) {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		mod  config.GoModule
+		want []string
+	}{
+		{
+			name: "module",
+			mod:  config.GoModule{Dir: wd, Path: "example.com/app", Go: "1.22"},
+			want: []string{"Go module: example.com/app, requires go 1.22"},
+		},
+		{
+			name: "toolchain",
+			mod:  config.GoModule{Dir: wd, Path: "example.com/app", Go: "1.21", Toolchain: "go1.22.1"},
+			want: []string{"Go module: example.com/app, requires go 1.21 (toolchain go1.22.1)"},
+		},
+		{
+			name: "no go directive",
+			mod:  config.GoModule{Dir: wd, Path: "example.com/app"},
+			want: []string{"Go module: example.com/app"},
+		},
+		{
+			name: "workspace",
+			mod: config.GoModule{
+				Dir:  wd,
+				Path: "example.com/api",
+				Go:   "1.22",
+				Work: filepath.Join(filepath.Dir(wd), "go.work"),
+				Use:  []string{"./api", "./lib"},
+			},
+			want: []string{
+				"Go module: example.com/api, requires go 1.22",
+				"Go workspace: " + filepath.Join("..", "go.work") + " (2 modules)",
+			},
+		},
+		{
+			name: "workspace root",
+			mod:  config.GoModule{Work: filepath.Join(wd, "go.work"), Use: []string{"./api"}},
+			want: []string{"Go workspace: go.work (1 module)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := goModuleLines(&tt.mod); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("goModuleLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestShowStatus_ExitCodes tests that tb status fails like the other
+// commands when the config cannot be loaded or the context is unknown
+func TestShowStatus_ExitCodes(t *testing.T) {
+	defer func(file, ctx string) { cfgFile, forceCtx = file, ctx }(cfgFile, forceCtx)
+	t.Setenv("HOME", t.TempDir())
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatalf("failed to create go.mod: %v", err)
+	}
+	badConfig := filepath.Join(tmpDir, "bad.yaml")
+	if err := os.WriteFile(badConfig, []byte("contexts: [\n"), 0644); err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cfgFile string
+		context string
+		want    int
+	}{
+		{name: "detected context", want: 0},
+		{name: "forced context", context: "go", want: 0},
+		{name: "invalid config", cfgFile: badConfig, want: ExitConfig},
+		{name: "unknown context", context: "nope", want: ExitContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile, forceCtx = tt.cfgFile, tt.context
+			if got := ExitCode(showStatus()); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/bamf0/toolbox/internal/shellwords"
+)
+
+// templateData holds the values available to {{...}} expressions in command
+// strings. Expansion happens after the command line has been split into
+// words, so every expanded word stays exactly one argument no matter what
+// the values contain.
+type templateData struct {
+	// ProjectName is the base name of ProjectRoot
+	ProjectName string
+
+	// ProjectRoot is the repository root, or the current directory outside a repository
+	ProjectRoot string
+
+	// Context is the context the command was resolved in
+	Context string
+
+	// Env exposes the process environment; unset variables expand to ""
+	Env map[string]string
+
+	// Vars holds variables provided by plugins
+	Vars map[string]string
+
+	// workDir is the project root commands run in unless they set dir;
+	// "" is the current directory
+	workDir string
+
+	git   *gitInfo
+	quote func(string) string
+	args  *argBinder
+}
+
+// gitInfo holds git values that are only looked up when a template uses them
+type gitInfo struct {
+	ctx    context.Context
+	dir    string
+	once   sync.Once
+	branch string
+	commit string
+}
+
+// newTemplateData collects the template values for a command run from dir
+func newTemplateData(ctx context.Context, dir, contextName string) *templateData {
+	root := findRepositoryRoot(dir)
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+
+	return &templateData{
+		ProjectName: filepath.Base(root),
+		ProjectRoot: root,
+		Context:     contextName,
+		Env:         env,
+		Vars:        make(map[string]string),
+		git:         &gitInfo{ctx: ctx, dir: root},
+	}
+}
+
+// commandDir returns the directory a command runs in: the project root by
+// default, the current directory for dir: cwd, and otherwise dir relative to
+// the project root. "" is the current directory.
+func commandDir(command config.Command, data *templateData) string {
+	var root string
+	if data != nil {
+		root = data.workDir
+	}
+	switch {
+	case command.Dir == config.WorkingDir:
+		return ""
+	case command.Dir == "":
+		return root
+	case root == "" || filepath.IsAbs(command.Dir):
+		return command.Dir
+	}
+	return filepath.Join(root, command.Dir)
+}
+
+// forContext returns a copy of the data for a command from another context,
+// such as a step found in the global context
+func (d *templateData) forContext(contextName string) *templateData {
+	if d == nil || d.Context == contextName {
+		return d
+	}
+	c := *d
+	c.Context = contextName
+	return &c
+}
+
+// withArgs returns a copy of the data whose {{args}} and {{arg N}}
+// placeholders expand to userArgs
+func (d *templateData) withArgs(params, userArgs []string) *templateData {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	c.args = newArgBinder(params, userArgs)
+	return &c
+}
+
+// funcs returns the template functions that place user arguments
+func (d *templateData) funcs() template.FuncMap {
+	binder := d.args
+	if binder == nil {
+		binder = newArgBinder(nil, nil)
+	}
+
+	return template.FuncMap{
+		"arg": func(ref interface{}) (string, error) {
+			arg, err := binder.arg(ref)
+			if err != nil {
+				return "", err
+			}
+			return d.quoteValue(arg), nil
+		},
+		"args": func() (string, error) {
+			if d.quote == nil {
+				return "", fmt.Errorf("{{args}} must be a word of its own")
+			}
+			rest := binder.rest()
+			quoted := make([]string, len(rest))
+			for i, arg := range rest {
+				quoted[i] = d.quote(arg)
+			}
+			return strings.Join(quoted, " "), nil
+		},
+	}
+}
+
+// GitBranch returns the current branch, or "" outside a git repository
+func (d *templateData) GitBranch() string {
+	if d.git == nil {
+		return ""
+	}
+	d.git.load()
+	return d.quoteValue(d.git.branch)
+}
+
+// GitCommit returns the abbreviated HEAD commit, or "" outside a git repository
+func (d *templateData) GitCommit() string {
+	if d.git == nil {
+		return ""
+	}
+	d.git.load()
+	return d.quoteValue(d.git.commit)
+}
+
+// quoteValue applies the data's quoting, if any, to a lazily computed value
+func (d *templateData) quoteValue(value string) string {
+	if d.quote == nil {
+		return value
+	}
+	return d.quote(value)
+}
+
+// quoted returns a copy of the data whose values are all passed through
+// quote, for expanding templates inside shell scripts
+func (d *templateData) quoted(quote func(string) string) *templateData {
+	q := *d
+	q.quote = quote
+	q.ProjectName = quote(d.ProjectName)
+	q.ProjectRoot = quote(d.ProjectRoot)
+	q.Context = quote(d.Context)
+	q.Env = quoteValues(d.Env, quote)
+	q.Vars = quoteValues(d.Vars, quote)
+	return &q
+}
+
+// quoteValues returns a copy of values with every value quoted
+func quoteValues(values map[string]string, quote func(string) string) map[string]string {
+	quoted := make(map[string]string, len(values))
+	for key, value := range values {
+		quoted[key] = quote(value)
+	}
+	return quoted
+}
+
+// load queries git once, and only when a template asks for it
+func (g *gitInfo) load() {
+	g.once.Do(func() {
+		g.branch = gitOutput(g.ctx, g.dir, "rev-parse", "--abbrev-ref", "HEAD")
+		g.commit = gitOutput(g.ctx, g.dir, "rev-parse", "--short", "HEAD")
+	})
+}
+
+// gitOutput runs a read-only git query and returns its trimmed output
+func gitOutput(ctx context.Context, dir string, args ...string) string {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// findRepositoryRoot returns the nearest directory at or above dir that
+// contains .git, or dir itself when there is none
+func findRepositoryRoot(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	if root, found := contextpkg.RepositoryRoot(absDir); found {
+		return root
+	}
+	return absDir
+}
+
+// expandTemplate expands the {{...}} expressions in a single word
+func expandTemplate(word string, data *templateData) (string, error) {
+	if data == nil || !strings.Contains(word, "{{") {
+		return word, nil
+	}
+
+	// Templates are checked when the configuration is loaded, so one that
+	// does not parse or expand is a configuration error
+	tmpl, err := config.ParseTemplate(word)
+	if err != nil {
+		return "", withExitCode(ExitConfig, fmt.Errorf("invalid template %q: %w", word, err))
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Funcs(data.funcs()).Execute(&buf, data); err != nil {
+		// A missing argument is the user's mistake, not the template's, so
+		// report it without the text/template wrapping
+		var missing *missingArgError
+		if errors.As(err, &missing) {
+			return "", withExitCode(ExitUsage, missing)
+		}
+		return "", withExitCode(ExitConfig, fmt.Errorf("failed to expand template %q: %w", word, err))
+	}
+	return buf.String(), nil
+}
+
+// expandCommand splits the command line into words with shell quoting rules
+// and expands templates in every word of the command line, the
+// fixed args, the working directory and the environment values. Each word is
+// expanded on its own, so a value containing spaces or shell metacharacters
+// never turns into more than one argument. User arguments go where the
+// {{args}} and {{arg N}} placeholders are, or at the end when there are none.
+func expandCommand(command config.Command, data *templateData, userArgs []string) ([]string, config.Command, error) {
+	// Without template data the words are used as they are, so single
+	// quotes must not escape anything
+	split := shellwords.SplitTemplate
+	if data == nil {
+		split = shellwords.Split
+	}
+	words, err := split(command.Run)
+	if err != nil {
+		return nil, command, fmt.Errorf("invalid command line: %w", err)
+	}
+
+	data = data.withArgs(command.Params, userArgs)
+	argv, err := expandWords(append(words, command.Args...), data)
+	if err != nil {
+		return nil, command, err
+	}
+
+	command, err = expandSettings(command, data)
+	if err != nil {
+		return nil, command, err
+	}
+
+	if data == nil || !data.args.bound {
+		return append(argv, userArgs...), command, nil
+	}
+	if err := data.args.unused(); err != nil {
+		return nil, command, err
+	}
+	return argv, command, nil
+}
+
+// expandScript expands templates in a shell script and returns it with its
+// positional parameters: the fixed args, then the user arguments the
+// script does not place with {{args}} or {{arg N}}. Scripts read those
+// through "$@", so they are not an error as for other commands. Every value
+// is quoted for the target shell, so it is substituted as a single literal
+// word.
+func expandScript(command config.Command, data *templateData, userArgs []string) (string, []string, config.Command, error) {
+	script := command.Run
+	data = data.withArgs(command.Params, userArgs)
+	if data != nil && strings.Contains(script, "{{") {
+		quoted := data.quoted(shellQuoter(command.Shell))
+
+		// {{args}} leaves out the arguments {{arg N}} uses anywhere in the
+		// script, so find those first
+		if _, err := expandTemplate(script, quoted); err != nil {
+			return "", nil, command, err
+		}
+
+		var err error
+		script, err = expandTemplate(script, quoted)
+		if err != nil {
+			return "", nil, command, err
+		}
+	}
+
+	args, err := expandWords(command.Args, data)
+	if err != nil {
+		return "", nil, command, err
+	}
+
+	command, err = expandSettings(command, data)
+	if err != nil {
+		return "", nil, command, err
+	}
+	command.Args = args
+
+	if data == nil || !data.args.bound {
+		return script, append(args, userArgs...), command, nil
+	}
+	if data.args.spread {
+		return script, args, command, nil
+	}
+	return script, append(args, data.args.remaining()...), command, nil
+}
+
+// expandWords expands templates in each word on its own. A word that is
+// just {{args}} becomes the user arguments no {{arg N}} took, which may be
+// any number of words.
+func expandWords(words []string, data *templateData) ([]string, error) {
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	expanded := make([]string, 0, len(words))
+	var spread []int
+	for _, word := range words {
+		if data != nil && argsPlaceholder.MatchString(word) {
+			spread = append(spread, len(expanded))
+			expanded = append(expanded, "")
+			continue
+		}
+		value, err := expandTemplate(word, data)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, value)
+	}
+
+	if len(spread) == 0 {
+		return expanded, nil
+	}
+
+	rest := data.args.rest()
+	result := make([]string, 0, len(expanded)+len(spread)*len(rest))
+	for i, word := range expanded {
+		if len(spread) > 0 && spread[0] == i {
+			result = append(result, rest...)
+			spread = spread[1:]
+			continue
+		}
+		result = append(result, word)
+	}
+	return result, nil
+}
+
+// expandSettings expands templates in the working directory and the
+// environment values
+func expandSettings(command config.Command, data *templateData) (config.Command, error) {
+	dir, err := expandTemplate(command.Dir, data)
+	if err != nil {
+		return command, err
+	}
+	command.Dir = dir
+
+	if len(command.Env) > 0 {
+		env := make(map[string]string, len(command.Env))
+		for key, value := range command.Env {
+			expanded, err := expandTemplate(value, data)
+			if err != nil {
+				return command, err
+			}
+			env[key] = expanded
+		}
+		command.Env = env
+	}
+
+	return command, nil
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// TestExpandCommand tests template expansion of command words, args, dir and env
+func TestExpandCommand(t *testing.T) {
+	data := &templateData{
+		ProjectName: "my app",
+		ProjectRoot: "/work/my app",
+		Context:     "go",
+		Env:         map[string]string{"TARGET": "linux; rm -rf /"},
+		Vars:        map[string]string{"image": "my-app"},
+	}
+
+	tests := []struct {
+		name    string
+		command config.Command
+		want    []string
+		wantDir string
+		wantEnv map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "no templates",
+			command: config.Command{Run: "go build ./..."},
+			want:    []string{"go", "build", "./..."},
+		},
+		{
+			name:    "value with spaces is one argument",
+			command: config.Command{Run: "echo {{.ProjectName}}"},
+			want:    []string{"echo", "my app"},
+		},
+		{
+			name:    "shell metacharacters stay literal",
+			command: config.Command{Run: "echo {{.Env.TARGET}}"},
+			want:    []string{"echo", "linux; rm -rf /"},
+		},
+		{
+			name:    "unset environment variable is empty",
+			command: config.Command{Run: "echo {{.Env.TB_UNSET}}"},
+			want:    []string{"echo", ""},
+		},
+		{
+			name:    "plugin variables and context",
+			command: config.Command{Run: "docker build -t {{.Vars.image}}:{{.Context}} ."},
+			want:    []string{"docker", "build", "-t", "my-app:go", "."},
+		},
+		{
+			name:    "args, dir and env are expanded",
+			command: config.Command{Run: "make", Args: []string{"NAME={{.ProjectName}}"}, Dir: "{{.ProjectRoot}}/sub", Env: map[string]string{"IMAGE": "{{.Vars.image}}"}},
+			want:    []string{"make", "NAME=my app"},
+			wantDir: "/work/my app/sub",
+			wantEnv: map[string]string{"IMAGE": "my-app"},
+		},
+		{
+			name:    "unknown field",
+			command: config.Command{Run: "echo {{.Nope}}"},
+			wantErr: true,
+		},
+		{
+			name:    "quoted words keep their spaces",
+			command: config.Command{Run: `git commit -m "release {{.Context}}" --author='A B'`},
+			want:    []string{"git", "commit", "-m", "release go", "--author=A B"},
+		},
+		{
+			name:    "template with spaces stays one word",
+			command: config.Command{Run: `docker build -t {{ index .Vars "image" }} .`},
+			want:    []string{"docker", "build", "-t", "my-app", "."},
+		},
+		{
+			name:    "single quotes keep templates literal",
+			command: config.Command{Run: `docker ps --filter name={{.ProjectName}} --format '{{.Names}}: {{ .Status }}'`},
+			want:    []string{"docker", "ps", "--filter", "name=my app", "--format", "{{.Names}}: {{ .Status }}"},
+		},
+		{
+			name:    "escaped template in a fixed arg",
+			command: config.Command{Run: "gh pr list", Args: []string{`--template={{"{{"}}.title}}`}},
+			want:    []string{"gh", "pr", "list", "--template={{.title}}"},
+		},
+		{
+			name:    "unbalanced quote",
+			command: config.Command{Run: `echo "oops`},
+			wantErr: true,
+		},
+		{
+			name:    "malformed template",
+			command: config.Command{Run: "echo {{.ProjectName"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, expanded, err := expandCommand(tt.command, data, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expandCommand() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandCommand() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandCommand() = %q, want %q", got, tt.want)
+			}
+			if expanded.Dir != tt.wantDir {
+				t.Errorf("expandCommand() dir = %q, want %q", expanded.Dir, tt.wantDir)
+			}
+			if tt.wantEnv != nil && !reflect.DeepEqual(expanded.Env, tt.wantEnv) {
+				t.Errorf("expandCommand() env = %v, want %v", expanded.Env, tt.wantEnv)
+			}
+		})
+	}
+}
+
+// TestExpandCommand_NilData tests that templates are left alone without data
+func TestExpandCommand_NilData(t *testing.T) {
+	got, _, err := expandCommand(config.Command{Run: "echo {{.ProjectName}}"}, nil, nil)
+	if err != nil {
+		t.Fatalf("expandCommand() unexpected error: %v", err)
+	}
+	want := []string{"echo", "{{.ProjectName}}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandCommand() = %q, want %q", got, want)
+	}
+}
+
+// TestNewTemplateData tests project and git values
+func TestNewTemplateData(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	subDir := filepath.Join(root, "pkg", "sub")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	t.Setenv("TB_TEMPLATE_TEST", "value")
+
+	data := newTemplateData(context.Background(), subDir, "node")
+
+	if data.ProjectRoot != root {
+		t.Errorf("ProjectRoot = %q, want %q", data.ProjectRoot, root)
+	}
+	if data.ProjectName != "project" {
+		t.Errorf("ProjectName = %q, want %q", data.ProjectName, "project")
+	}
+	if data.Context != "node" {
+		t.Errorf("Context = %q, want %q", data.Context, "node")
+	}
+	if data.Env["TB_TEMPLATE_TEST"] != "value" {
+		t.Errorf("Env[TB_TEMPLATE_TEST] = %q, want %q", data.Env["TB_TEMPLATE_TEST"], "value")
+	}
+
+	// The fake .git directory is not a repository, so git values are empty
+	if branch := data.GitBranch(); branch != "" {
+		t.Errorf("GitBranch() = %q, want empty", branch)
+	}
+}
+
+// TestExecuteCommandSecure_Templates tests that expanded values reach the program intact
+func TestExecuteCommandSecure_Templates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	data := &templateData{
+		ProjectName: "two words",
+		Env:         map[string]string{},
+		Vars:        map[string]string{},
+	}
+
+	err := executeCommandSecure(context.Background(), config.Command{Run: "test {{.ProjectName}} = {{.ProjectName}}"}, data, nil)
+	if err != nil {
+		t.Errorf("executeCommandSecure() unexpected error = %v", err)
+	}
+
+	err = executeCommandSecure(context.Background(), config.Command{Run: "echo {{.Missing}}"}, data, nil)
+	if err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("executeCommandSecure() error = %v, want template error", err)
+	}
+}
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/bamf0/toolbox/internal/plugin"
+	"github.com/bamf0/toolbox/internal/registry"
+	"github.com/bamf0/toolbox/internal/workspace"
+)
+
+// projectRun is a project of the workspace and how a command ran in it
+type projectRun struct {
+	workspace.Project
+
+	// context supplies the command in this project, "" if none does
+	context string
+
+	status   string
+	duration time.Duration
+	err      error
+}
+
+// runWorkspace runs a command in every project of the workspace around the
+// working directory, up to jobs projects at a time. Each project runs its
+// own tb from its directory, so it uses its own configuration and context.
+// Projects that do not define the command are skipped; a failing project
+// does not stop the others.
+func runWorkspace(commandName string, commandArgs []string) error {
+	if cfgFile != "" {
+		return withExitCode(ExitUsage, errors.New("--config cannot be used with --all; every project loads its own configuration"))
+	}
+
+	ws, err := workspace.Find(".")
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load workspace: %w", err))
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+	pm := getPluginManager()
+	addPluginContexts(cfg, pm)
+
+	// A directory is a project when a context is detected in it, not only
+	// in a parent
+	detector := newDetector(cfg, pm)
+	found, err := ws.Discover(func(dir string) bool {
+		candidates, _ := detector.DetectAll(dir)
+		for _, c := range candidates {
+			if c.Distance == 0 {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to find projects: %w", err))
+	}
+	found, err = workspace.Filter(found, onlyProjects)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --only pattern: %w", err))
+	}
+	if len(found) == 0 {
+		return withExitCode(ExitContext, fmt.Errorf("no projects found in %s", ws.Root))
+	}
+
+	if verbose {
+		if ws.File != "" {
+			fmt.Printf("Workspace file: %s\n", ws.File)
+		} else {
+			fmt.Printf("Workspace: %s (scanned %d levels)\n", ws.Root, ws.Depth)
+		}
+	}
+
+	// Resolve the command everywhere first, so that projects without it are
+	// skipped and every project that runs shows its context
+	runs := make([]*projectRun, len(found))
+	var pending []int
+	for i, p := range found {
+		run := &projectRun{Project: p}
+		runs[i] = run
+		run.context, run.err = resolveInProject(p.Dir, pm, commandName)
+		switch {
+		case run.err == nil:
+			pending = append(pending, i)
+		case errors.Is(run.err, registry.ErrUnknownCommand), errors.Is(run.err, contextpkg.ErrNoContext):
+			run.status = "skipped"
+			run.err = nil
+		default:
+			run.status = "error"
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", p.Path, run.err)
+		}
+	}
+	if allSkipped(runs) {
+		return withExitCode(ExitUnknownCommand, fmt.Errorf("command '%s' not found in any project", commandName))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the tb executable: %w", err)
+	}
+	args := workspaceArgs(commandName, commandArgs)
+	label := strings.Join(append([]string{commandName}, commandArgs...), " ")
+
+	interrupted := runProjects(runs, pending, exe, args, label)
+
+	printProjectSummary(runs)
+
+	if interrupted != nil {
+		return interrupted
+	}
+	var failed []string
+	for _, run := range runs {
+		if run.err != nil {
+			failed = append(failed, run.Path)
+		}
+	}
+	if len(failed) > 0 {
+		return withExitCode(ExitFailure, fmt.Errorf("%d of %d projects failed: %s", len(failed), len(runs), strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// allSkipped reports whether no project defines the command
+func allSkipped(runs []*projectRun) bool {
+	for _, run := range runs {
+		if run.status != "skipped" {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveInProject returns the context that supplies a command in dir, with
+// the configuration and detection tb would use there
+func resolveInProject(dir string, pm *plugin.PluginManager, commandName string) (string, error) {
+	cfg, err := config.LoadDir("", dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	addPluginContexts(cfg, pm)
+
+	chain := [][]string{{forceCtx}}
+	if forceCtx == "" {
+		candidates, err := newDetector(cfg, pm).DetectAll(dir)
+		if err != nil {
+			return "", err
+		}
+		if len(candidates) == 0 {
+			return "", contextpkg.ErrNoContext
+		}
+		chain = resolutionChain(candidates)
+	}
+
+	plan, err := registry.New(cfg).Resolve(chain, commandName)
+	if err != nil {
+		return "", err
+	}
+	return plan.Context, nil
+}
+
+// addPluginContexts adds the contexts of plugins that the configuration
+// does not define
+func addPluginContexts(cfg *config.Config, pm *plugin.PluginManager) {
+	for ctxName, ctxConfig := range pm.GetContexts() {
+		if _, exists := cfg.Contexts[ctxName]; !exists {
+			cfg.Contexts[ctxName] = ctxConfig
+		}
+	}
+}
+
+// workspaceArgs returns the arguments every project's tb runs with: the
+// flags that apply to a single project, the command and its arguments.
+// --jobs is not passed on, as it sets how many projects run at once.
+func workspaceArgs(commandName string, commandArgs []string) []string {
+	var args []string
+	if forceCtx != "" {
+		args = append(args, "--context", forceCtx)
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+	if noHooks {
+		args = append(args, "--no-hooks")
+	}
+	if timeoutFlagSet {
+		args = append(args, "--timeout", commandTimeout.String())
+	}
+	if gracePeriod != DefaultGracePeriod {
+		args = append(args, "--grace-period", gracePeriod.String())
+	}
+	args = append(args, commandName)
+	return append(args, commandArgs...)
+}
+
+// runProjects runs tb with args in the pending projects, up to jobs at a
+// time. One project at a time runs attached to the terminal, after a header
+// with label; several at once get their output prefixed with the project
+// path and no input. A signal stops the running projects and keeps new ones
+// from starting; the error it caused is returned.
+func runProjects(runs []*projectRun, pending []int, exe string, args []string, label string) error {
+	limit := jobs
+	if limit < 1 {
+		limit = 1
+	}
+	parallel := limit > 1 && len(pending) > 1
+
+	width := 0
+	for _, i := range pending {
+		if len(runs[i].Path) > width {
+			width = len(runs[i].Path)
+		}
+	}
+	color := useColor(os.Stdout)
+	var outputMu sync.Mutex
+
+	done := make(chan int)
+	running := 0
+	next := 0
+	var interrupted error
+
+	start := func(n int) {
+		run := runs[pending[n]]
+		run.status = "running"
+
+		cmd := exec.Command(exe, args...)
+		cmd.Dir = run.Dir
+		cmd.Env = os.Environ()
+
+		var flush func()
+		if parallel {
+			prefix := stepPrefix(run.Path, n, width, color)
+			stdout := newPrefixWriter(&outputMu, os.Stdout, prefix)
+			stderr := newPrefixWriter(&outputMu, os.Stderr, prefix)
+			cmd.Stdout, cmd.Stderr = stdout, stderr
+			flush = func() {
+				stdout.Flush()
+				stderr.Flush()
+			}
+		} else {
+			fmt.Printf("==> %s (%s): %s\n", run.Path, run.context, label)
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		}
+
+		running++
+		go func() {
+			began := time.Now()
+			err := runProcess(context.Background(), cmd)
+			if flush != nil {
+				flush()
+			}
+			run.err = err
+			run.duration = time.Since(began)
+			done <- pending[n]
+		}()
+	}
+
+	for {
+		for running < limit && next < len(pending) && interrupted == nil {
+			start(next)
+			next++
+		}
+		if running == 0 {
+			break
+		}
+
+		run := runs[<-done]
+		running--
+		run.status = stepStatus(run.err)
+
+		if stoppedBySignal(run.err) && interrupted == nil {
+			interrupted = run.err
+		}
+	}
+
+	for _, i := range pending[next:] {
+		runs[i].status = "cancelled"
+	}
+	return interrupted
+}
+
+// stoppedBySignal reports whether a project was stopped by a signal meant
+// for the whole run: one tb received, or one its command received from the
+// terminal, after which the project's tb exits with 128+N
+func stoppedBySignal(err error) bool {
+	var interrupted *interruptError
+	if errors.As(err, &interrupted) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	for _, sig := range forwardedSignals {
+		if s, ok := sig.(syscall.Signal); ok && exitErr.ExitCode() == exitSignal+int(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// printProjectSummary prints the context, status and duration of every
+// project
+func printProjectSummary(runs []*projectRun) {
+	width := len("PROJECT")
+	ctxWidth := len("CONTEXT")
+	for _, run := range runs {
+		if len(run.Path) > width {
+			width = len(run.Path)
+		}
+		if len(run.context) > ctxWidth {
+			ctxWidth = len(run.context)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%-*s  %-*s  %-10s  %s\n", width, "PROJECT", ctxWidth, "CONTEXT", "STATUS", "DURATION")
+	for _, run := range runs {
+		ctxName := run.context
+		if ctxName == "" {
+			ctxName = "-"
+		}
+		duration := "-"
+		if run.duration > 0 {
+			duration = run.duration.Round(time.Millisecond).String()
+		}
+		fmt.Printf("%-*s  %-*s  %-10s  %s\n", width, run.Path, ctxWidth, ctxName, run.status, duration)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"github.com/bamf0/toolbox/internal/registry"
+)
+
+// TestWorkspaceArgs tests which flags are passed on to each project
+func TestWorkspaceArgs(t *testing.T) {
+	defer func(ctx string, dry, verb, hooks, set bool, timeout, grace time.Duration) {
+		forceCtx, dryRun, verbose, noHooks, timeoutFlagSet, commandTimeout, gracePeriod = ctx, dry, verb, hooks, set, timeout, grace
+	}(forceCtx, dryRun, verbose, noHooks, timeoutFlagSet, commandTimeout, gracePeriod)
+
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{
+			name:  "no flags",
+			setup: func() {},
+			want:  []string{"test", "-run", "TestX"},
+		},
+		{
+			name: "every flag",
+			setup: func() {
+				forceCtx, dryRun, verbose, noHooks = "go", true, true, true
+				timeoutFlagSet, commandTimeout, gracePeriod = true, time.Minute, time.Second
+			},
+			want: []string{"--context", "go", "--dry-run", "--verbose", "--no-hooks", "--timeout", "1m0s", "--grace-period", "1s", "test", "-run", "TestX"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forceCtx, dryRun, verbose, noHooks = "", false, false, false
+			timeoutFlagSet, commandTimeout, gracePeriod = false, DefaultCommandTimeout, DefaultGracePeriod
+			tt.setup()
+
+			got := workspaceArgs("test", []string{"-run", "TestX"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workspaceArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestResolveInProject tests that a project's own configuration and
+// context decide whether it defines a command
+func TestResolveInProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":                 "",
+		"api/go.mod":                "module api\n",
+		"web/package.json":          "{}\n",
+		"web/.toolbox.yaml":         "contexts:\n  node:\n    commands:\n      e2e: npx playwright test\n",
+		"docs/index.md":             "",
+		"services/worker/go.mod":    "module worker\n",
+		"services/worker/Makefile":  "all:\n\nclean:\n\trm -rf bin\n",
+		"services/worker/README.md": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		dir     string
+		command string
+		want    string
+		wantErr error
+	}{
+		{dir: "api", command: "test", want: "go"},
+		{dir: "web", command: "test", want: "node"},
+		{dir: "web", command: "e2e", want: "node"},
+		{dir: "api", command: "e2e", wantErr: registry.ErrUnknownCommand},
+		{dir: "docs", command: "test", wantErr: contextpkg.ErrNoContext},
+		{dir: "services/worker", command: "clean", want: "make"},
+	}
+
+	pm := getPluginManager()
+	for _, tt := range tests {
+		t.Run(tt.dir+" "+tt.command, func(t *testing.T) {
+			got, err := resolveInProject(filepath.Join(root, filepath.FromSlash(tt.dir)), pm, tt.command)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("resolveInProject() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveInProject() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveInProject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
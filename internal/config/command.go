@@ -0,0 +1,299 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bamf0/toolbox/internal/shellwords"
+	"gopkg.in/yaml.v3"
+)
+
+// Command is a single command definition. In YAML it is written either as a
+// plain command line or as a mapping with per-command settings:
+//
+//	commands:
+//	  build: go build ./...
+//	  dev:
+//	    run: npm run dev
+//	    timeout: none
+//	    env:
+//	      NODE_ENV: development
+type Command struct {
+	// Run is the command line to execute
+	Run string `yaml:"run"`
+
+	// Description is shown in help, status and completion output
+	Description string `yaml:"description,omitempty"`
+
+	// Env holds extra environment variables for this command only
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// Dir is the working directory, relative to the project root the
+	// context was detected in, where commands run by default. WorkingDir
+	// keeps the directory tb was started in.
+	Dir string `yaml:"dir,omitempty"`
+
+	// Timeout overrides the default command timeout. Nil means the default
+	// applies; zero disables the timeout.
+	Timeout *time.Duration `yaml:"timeout,omitempty"`
+
+	// Confirm asks for confirmation before the command runs
+	Confirm bool `yaml:"confirm,omitempty"`
+
+	// Args are appended to Run as-is, one argument per entry, before any
+	// arguments given on the command line
+	Args []string `yaml:"args,omitempty"`
+
+	// Shell runs Run as a script through bash, sh or pwsh instead of
+	// executing it directly. Args and command-line arguments become the
+	// script's positional parameters.
+	Shell string `yaml:"shell,omitempty"`
+
+	// Steps names the commands a composite command runs. Names are looked up
+	// in the same context first, then in the global context.
+	Steps []string `yaml:"steps,omitempty"`
+
+	// Needs names commands that must succeed before this one runs
+	Needs []string `yaml:"needs,omitempty"`
+
+	// ContinueOnError keeps running the remaining steps after a step fails.
+	// Steps that need the failed step are still skipped.
+	ContinueOnError bool `yaml:"continue_on_error,omitempty"`
+
+	// Params names the command-line arguments in order, so that
+	// {{arg "name"}} can place them
+	Params []string `yaml:"params,omitempty"`
+
+	// Hooks run around this command
+	Hooks `yaml:",inline"`
+}
+
+// WorkingDir is the dir value that runs a command in the directory tb was
+// started in instead of the project root
+const WorkingDir = "cwd"
+
+// Hooks are commands run around a command, or around every command run in a
+// context. Each list may be written as a single command or a list.
+type Hooks struct {
+	// Before runs first; if a hook fails, the command does not run
+	Before HookList `yaml:"before,omitempty"`
+
+	// After runs once the command has finished, whether or not it succeeded
+	After HookList `yaml:"after,omitempty"`
+
+	// OnFailure runs when the command or one of its before hooks failed
+	OnFailure HookList `yaml:"on_failure,omitempty"`
+}
+
+// HookList is a list of hook commands
+type HookList []Command
+
+// UnmarshalYAML accepts a single command as well as a list of commands
+func (h *HookList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		var cmd Command
+		if err := cmd.UnmarshalYAML(node); err != nil {
+			return err
+		}
+		*h = HookList{cmd}
+		return nil
+	}
+
+	hooks := make(HookList, 0, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind == yaml.SequenceNode {
+			return &definitionError{line: item.Line, msg: "hook must be a command, not a list of steps"}
+		}
+		var cmd Command
+		if err := cmd.UnmarshalYAML(item); err != nil {
+			return err
+		}
+		hooks = append(hooks, cmd)
+	}
+	*h = hooks
+	return nil
+}
+
+// IsEmpty reports whether no hooks are set
+func (h Hooks) IsEmpty() bool {
+	return len(h.Before) == 0 && len(h.After) == 0 && len(h.OnFailure) == 0
+}
+
+// SupportedShells lists the values accepted for a command's shell field
+var SupportedShells = []string{"bash", "sh", "pwsh"}
+
+// commandFields lists the keys accepted in the mapping form of a command
+var commandFields = map[string]bool{
+	"run":         true,
+	"description": true,
+	"env":         true,
+	"dir":         true,
+	"timeout":     true,
+	"confirm":     true,
+	"args":        true,
+	"shell":       true,
+	"steps":       true,
+	"needs":       true,
+	"before":      true,
+	"after":       true,
+	"params":      true,
+
+	"continue_on_error": true,
+	"on_failure":        true,
+}
+
+// definitionError reports a malformed command definition. Its message only
+// names keys and line numbers, never values from the file.
+type definitionError struct {
+	line int
+	msg  string
+}
+
+func (e *definitionError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// UnmarshalYAML accepts both the plain string and the mapping form
+func (c *Command) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			*c = Command{}
+			return nil
+		}
+		*c = Command{Run: node.Value}
+		return nil
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if !commandFields[key.Value] {
+				return &definitionError{line: key.Line, msg: fmt.Sprintf("unknown command field %q", key.Value)}
+			}
+		}
+
+		var raw struct {
+			Run         string            `yaml:"run"`
+			Description string            `yaml:"description"`
+			Env         map[string]string `yaml:"env"`
+			Dir         string            `yaml:"dir"`
+			Timeout     string            `yaml:"timeout"`
+			Confirm     bool              `yaml:"confirm"`
+			Args        []string          `yaml:"args"`
+			Shell       string            `yaml:"shell"`
+			Steps       []string          `yaml:"steps"`
+			Needs       []string          `yaml:"needs"`
+			Before      HookList          `yaml:"before"`
+			After       HookList          `yaml:"after"`
+			Params      []string          `yaml:"params"`
+
+			ContinueOnError bool     `yaml:"continue_on_error"`
+			OnFailure       HookList `yaml:"on_failure"`
+		}
+		if err := node.Decode(&raw); err != nil {
+			var defErr *definitionError
+			if errors.As(err, &defErr) {
+				return err
+			}
+			return &definitionError{line: node.Line, msg: "invalid command definition"}
+		}
+
+		timeout, err := parseTimeout(raw.Timeout)
+		if err != nil {
+			return &definitionError{line: node.Line, msg: err.Error()}
+		}
+
+		*c = Command{
+			Run:         raw.Run,
+			Description: raw.Description,
+			Env:         raw.Env,
+			Dir:         raw.Dir,
+			Timeout:     timeout,
+			Confirm:     raw.Confirm,
+			Args:        raw.Args,
+			Shell:       raw.Shell,
+			Steps:       raw.Steps,
+			Needs:       raw.Needs,
+			Params:      raw.Params,
+			Hooks: Hooks{
+				Before:    raw.Before,
+				After:     raw.After,
+				OnFailure: raw.OnFailure,
+			},
+
+			ContinueOnError: raw.ContinueOnError,
+		}
+		return nil
+
+	case yaml.SequenceNode:
+		steps := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode || item.ShortTag() != "!!str" {
+				return &definitionError{line: item.Line, msg: "composite command steps must be command names"}
+			}
+			steps = append(steps, item.Value)
+		}
+		*c = Command{Steps: steps}
+		return nil
+
+	default:
+		return &definitionError{line: node.Line, msg: "command must be a string, a list of steps or a mapping"}
+	}
+}
+
+// parseTimeout parses a per-command timeout. An empty value leaves the
+// default in place; "0" and "none" disable the timeout.
+func parseTimeout(value string) (*time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if value == "none" || value == "0" {
+		none := time.Duration(0)
+		return &none, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout (use a duration like 30s or 15m, or none)")
+	}
+	return &d, nil
+}
+
+// IsComposite reports whether the command runs other commands
+func (c Command) IsComposite() bool {
+	return len(c.Steps) > 0
+}
+
+// String renders the command line, including any fixed args. Composite
+// commands render as their list of steps.
+func (c Command) String() string {
+	if c.IsComposite() {
+		return "[" + strings.Join(c.Steps, ", ") + "]"
+	}
+	if len(c.Args) == 0 {
+		return c.Run
+	}
+
+	return c.Run + " " + shellwords.Join(c.Args)
+}
+
+// EnvList returns the command's environment variables as KEY=value pairs,
+// sorted by key
+func (c Command) EnvList() []string {
+	keys := make([]string, 0, len(c.Env))
+	for key := range c.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+c.Env[key])
+	}
+	return env
+}
@@ -4,11 +4,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	contextpkg "github.com/bamf0/toolbox/internal/context"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,21 +26,64 @@ const (
 
 	// MaxCommandsPerContext limits commands per context
 	MaxCommandsPerContext = 50
+
+	// MaxCommandArgs limits the fixed args of a single command
+	MaxCommandArgs = 100
+
+	// MaxDetectRules limits the detection markers and rules of a context
+	MaxDetectRules = 20
+
+	// MaxDetectPriority bounds the detection priority of a context
+	MaxDetectPriority = 100
+
+	// ProjectConfigName is the file name of project-level configuration
+	ProjectConfigName = ".toolbox.yaml"
 )
 
 // Config represents the toolbox configuration
 type Config struct {
 	Contexts map[string]ContextConfig `yaml:"contexts"`
+
+	// Sources lists the config files that were merged, lowest priority first.
+	// It is empty when only the built-in defaults are in use.
+	Sources []string `yaml:"-"`
 }
 
 // ContextConfig defines commands for a specific context
 type ContextConfig struct {
-	Commands     map[string]string `yaml:"commands"`
-	Descriptions map[string]string `yaml:"descriptions,omitempty"`
+	Commands     map[string]Command `yaml:"commands"`
+	Descriptions map[string]string  `yaml:"descriptions,omitempty"`
+
+	// Remove lists commands inherited from lower configuration layers
+	// (built-in defaults, the user file) that should not be available.
+	Remove []string `yaml:"remove,omitempty"`
+
+	// Hooks run once around every command invoked in this context
+	Hooks `yaml:",inline"`
+
+	// Detect lets the context be detected from files in the project
+	Detect DetectConfig `yaml:"detect,omitempty"`
 }
 
-// Load reads and parses the configuration file with security validation.
-// Priority: specified file > .toolbox.yaml (cwd) > ~/.toolbox/config.yaml > defaults
+// Description returns the description of a command, preferring the one set on
+// the command itself over the context's descriptions map.
+func (c ContextConfig) Description(name string) string {
+	if desc := c.Commands[name].Description; desc != "" {
+		return desc
+	}
+	return c.Descriptions[name]
+}
+
+// Load builds the configuration by layering every available source on top of
+// the built-in defaults. Later layers override earlier ones per command and
+// per description; project files are package.json, the Python project files,
+// go.mod, go.work, Cargo.toml, .cargo/config.toml, the makefile, justfile and
+// Taskfile:
+//
+//	built-in defaults < project files < ~/.toolbox/config.yaml < .toolbox.yaml < specified file
+//
+// .toolbox.yaml is the nearest one in the working directory or a parent, up
+// to the repository root (see FindProjectConfig).
 //
 // Security measures:
 //   - Path traversal prevention
@@ -46,32 +91,142 @@ type ContextConfig struct {
 //   - Content validation
 //   - Safe error messages
 func Load(cfgFile string) (*Config, error) {
-	// Try specified file first
+	return LoadDir(cfgFile, ".")
+}
+
+// LoadDir builds the configuration as Load does, for a project in dir
+// instead of the working directory: the project layer is the .toolbox.yaml
+// found from dir. A specified file is still relative to the working
+// directory.
+func LoadDir(cfgFile, dir string) (*Config, error) {
+	// Validate the specified config file path for security
 	if cfgFile != "" {
-		// Validate the config file path for security
 		if err := validateConfigPath(cfgFile); err != nil {
 			return nil, fmt.Errorf("invalid config path: %w", err)
 		}
-		return loadFromFile(cfgFile)
 	}
 
-	// Try local .toolbox.yaml
-	localConfig := ".toolbox.yaml"
-	if fileExists(localConfig) {
-		return loadFromFile(localConfig)
+	cfg := getDefaultConfig()
+
+	// The tooling, scripts, binaries and targets of the project refine the
+	// defaults; every config file still overrides them. The defaults these
+	// layers remove are only removed once the config files are merged, since
+	// a config file may define them again or depend on them.
+	layers := []*Config{
+		nodeLayer(dir), pythonLayer(dir), goLayer(dir), rustLayer(dir),
+		javaLayer(dir), makeLayer(dir), justLayer(dir), taskLayer(dir),
+	}
+	removed := make(map[string][]string)
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		for ctxName, ctx := range layer.Contexts {
+			removed[ctxName] = append(removed[ctxName], ctx.Remove...)
+			ctx.Remove = nil
+			layer.Contexts[ctxName] = ctx
+		}
+		mergeConfig(cfg, layer)
+	}
+
+	defined := make(map[string]bool)
+	for _, path := range configLayers(cfgFile, dir) {
+		layer, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfig(cfg, layer)
+		cfg.Sources = append(cfg.Sources, path)
+
+		for ctxName, ctx := range layer.Contexts {
+			for name := range ctx.Commands {
+				defined[ctxName+"/"+name] = true
+			}
+		}
+	}
+	removeUnused(cfg, removed, defined)
+
+	// Steps and needs may point at commands from any layer, so they are only
+	// fully checked once everything is merged
+	if err := validateReferences(cfg, true); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// removeUnused removes the commands of each context in removed, except for
+// those defined is set for and those another command refers to by steps or
+// needs. Keys of defined are "context/command".
+func removeUnused(cfg *Config, removed map[string][]string, defined map[string]bool) {
+	referenced := referencedCommands(cfg)
+	for ctxName, names := range removed {
+		ctx, exists := cfg.Contexts[ctxName]
+		if !exists {
+			continue
+		}
+		for _, name := range names {
+			key := ctxName + "/" + name
+			if defined[key] || referenced[key] {
+				continue
+			}
+			delete(ctx.Commands, name)
+			delete(ctx.Descriptions, name)
+		}
+	}
+}
+
+// configLayers returns the config files to apply for a project in dir,
+// lowest priority first. The specified file is always included so that a
+// missing file is reported.
+func configLayers(cfgFile, dir string) []string {
+	var layers []string
+	seen := make(map[string]bool)
+
+	add := func(path string) {
+		key := path
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		layers = append(layers, path)
 	}
 
-	// Try ~/.toolbox/config.yaml
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
+	// ~/.toolbox/config.yaml
+	if homeDir, err := os.UserHomeDir(); err == nil {
 		globalConfig := filepath.Join(homeDir, ".toolbox", "config.yaml")
 		if fileExists(globalConfig) {
-			return loadFromFile(globalConfig)
+			add(globalConfig)
 		}
 	}
 
-	// Return default configuration
-	return getDefaultConfig(), nil
+	// Project .toolbox.yaml in the project directory or a parent
+	if projectConfig, found := FindProjectConfig(dir); found {
+		add(projectConfig)
+	}
+
+	if cfgFile != "" {
+		add(cfgFile)
+	}
+
+	return layers
+}
+
+// FindProjectConfig searches dir and its parents for .toolbox.yaml.
+// The search stops after the first directory containing .git (the repository
+// root) or at the filesystem root, so a config outside the repository is
+// never picked up.
+func FindProjectConfig(dir string) (string, bool) {
+	configDir, found := contextpkg.WalkRepository(dir, func(searchDir string) bool {
+		return fileExists(filepath.Join(searchDir, ProjectConfigName))
+	})
+	if !found {
+		return "", false
+	}
+	return filepath.Join(configDir, ProjectConfigName), true
 }
 
 // validateConfigPath performs security checks on user-provided config paths
@@ -104,8 +259,9 @@ func validateConfigPath(path string) error {
 	return nil
 }
 
-// loadFromFile reads and parses a YAML config file with security checks
-func loadFromFile(path string) (*Config, error) {
+// readConfigFile reads and parses a YAML config file with security checks.
+// The result contains only what the file itself defines.
+func readConfigFile(path string) (*Config, error) {
 	// Check file exists and get size
 	fileInfo, err := os.Stat(path)
 	if err != nil {
@@ -132,6 +288,11 @@ func loadFromFile(path string) (*Config, error) {
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		// Structural errors only name fields and lines, so they are safe to show
+		var defErr *definitionError
+		if errors.As(err, &defErr) {
+			return nil, fmt.Errorf("failed to parse config file: %w", defErr)
+		}
 		// Sanitize YAML parsing errors to avoid leaking file content
 		return nil, fmt.Errorf("failed to parse config file: invalid YAML format")
 	}
@@ -141,9 +302,6 @@ func loadFromFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Merge with defaults for any missing contexts
-	mergeDefaults(&cfg)
-
 	return &cfg, nil
 }
 
@@ -170,14 +328,32 @@ func validateConfig(cfg *Config) error {
 		}
 
 		// Validate each command
-		for cmdName, cmdString := range ctxCfg.Commands {
-			if err := validateCommand(cmdName, cmdString); err != nil {
+		for cmdName, cmd := range ctxCfg.Commands {
+			if err := validateCommandDefinition(cmdName, cmd); err != nil {
 				return fmt.Errorf("context %q, command %q: %w", ctxName, cmdName, err)
 			}
 		}
+
+		if err := validateHooks(ctxCfg.Hooks); err != nil {
+			return fmt.Errorf("context %q: %w", ctxName, err)
+		}
+
+		if err := validateDetect(ctxCfg.Detect); err != nil {
+			return fmt.Errorf("context %q: %w", ctxName, err)
+		}
+
+		// Validate removals
+		for _, cmdName := range ctxCfg.Remove {
+			if cmdName == "" {
+				return fmt.Errorf("context %q: empty command name in remove list", ctxName)
+			}
+			if _, defined := ctxCfg.Commands[cmdName]; defined {
+				return fmt.Errorf("context %q, command %q: both defined and removed", ctxName, cmdName)
+			}
+		}
 	}
 
-	return nil
+	return validateReferences(cfg, false)
 }
 
 // validateContextName ensures context names are safe
@@ -228,6 +404,138 @@ func validateCommand(name, command string) error {
 	return nil
 }
 
+// validateCommandDefinition validates a command and its per-command settings
+func validateCommandDefinition(name string, cmd Command) error {
+	for _, ref := range cmd.References() {
+		if ref == "" || strings.ContainsAny(ref, " \t\n") {
+			return fmt.Errorf("invalid command reference %q", ref)
+		}
+	}
+
+	if err := validateHooks(cmd.Hooks); err != nil {
+		return err
+	}
+
+	if cmd.IsComposite() {
+		return validateCompositeCommand(name, cmd)
+	}
+
+	if err := validateCommand(name, cmd.Run); err != nil {
+		return err
+	}
+
+	// Commands without a shell are split into words and templates are
+	// expanded at run time; catch quoting and template mistakes now, while
+	// the context and command can be named
+	if err := validateTemplates(cmd); err != nil {
+		return err
+	}
+
+	if len(cmd.Description) > MaxCommandLength {
+		return fmt.Errorf("description exceeds maximum length of %d characters", MaxCommandLength)
+	}
+
+	for key := range cmd.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+	}
+
+	if cmd.Timeout != nil && *cmd.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	if cmd.Shell != "" && !isSupportedShell(cmd.Shell) {
+		return fmt.Errorf("unsupported shell %q (use %s)", cmd.Shell, strings.Join(SupportedShells, ", "))
+	}
+
+	if len(cmd.Args) > MaxCommandArgs {
+		return fmt.Errorf("too many args (max: %d, got: %d)", MaxCommandArgs, len(cmd.Args))
+	}
+	for i, arg := range cmd.Args {
+		if len(arg) > MaxCommandLength {
+			return fmt.Errorf("arg %d exceeds maximum length of %d characters", i, MaxCommandLength)
+		}
+	}
+
+	seen := make(map[string]bool, len(cmd.Params))
+	for _, param := range cmd.Params {
+		if param == "" || strings.ContainsAny(param, " \t\n\"{}") {
+			return fmt.Errorf("invalid parameter name %q", param)
+		}
+		if seen[param] {
+			return fmt.Errorf("duplicate parameter name %q", param)
+		}
+		seen[param] = true
+	}
+
+	return nil
+}
+
+// validateCompositeCommand checks that a composite command only sets fields
+// that apply to a list of steps
+func validateCompositeCommand(name string, cmd Command) error {
+	if name == "" {
+		return fmt.Errorf("empty command name")
+	}
+
+	if len(cmd.Steps) > MaxCommandsPerContext {
+		return fmt.Errorf("too many steps (max: %d, got: %d)", MaxCommandsPerContext, len(cmd.Steps))
+	}
+
+	if len(cmd.Description) > MaxCommandLength {
+		return fmt.Errorf("description exceeds maximum length of %d characters", MaxCommandLength)
+	}
+
+	switch {
+	case cmd.Run != "":
+		return fmt.Errorf("composite command cannot set both run and steps")
+	case cmd.Shell != "", len(cmd.Args) > 0, len(cmd.Params) > 0, len(cmd.Env) > 0, cmd.Dir != "", cmd.Timeout != nil:
+		return fmt.Errorf("composite command can only set steps, needs, description, confirm, continue_on_error and hooks")
+	}
+
+	return nil
+}
+
+// validateHooks checks that every hook is a plain command without hooks,
+// steps or needs of its own
+func validateHooks(hooks Hooks) error {
+	lists := []struct {
+		name  string
+		hooks HookList
+	}{
+		{"before", hooks.Before},
+		{"after", hooks.After},
+		{"on_failure", hooks.OnFailure},
+	}
+
+	for _, list := range lists {
+		if len(list.hooks) > MaxCommandsPerContext {
+			return fmt.Errorf("too many %s hooks (max: %d, got: %d)", list.name, MaxCommandsPerContext, len(list.hooks))
+		}
+		for i, hook := range list.hooks {
+			if hook.IsComposite() || len(hook.Needs) > 0 || !hook.Hooks.IsEmpty() || hook.Confirm {
+				return fmt.Errorf("%s hook %d: hooks cannot have steps, needs, hooks or confirm", list.name, i+1)
+			}
+			if err := validateCommandDefinition(list.name, hook); err != nil {
+				return fmt.Errorf("%s hook %d: %w", list.name, i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isSupportedShell reports whether shell is one of SupportedShells
+func isSupportedShell(shell string) bool {
+	for _, supported := range SupportedShells {
+		if shell == supported {
+			return true
+		}
+	}
+	return false
+}
+
 // containsDangerousPatterns checks for shell metacharacters
 func containsDangerousPatterns(s string) bool {
 	// Note: These are informational only for config files
@@ -254,17 +562,62 @@ func isAlphaNumeric(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
 }
 
-// mergeDefaults merges default configuration with user configuration
-func mergeDefaults(cfg *Config) {
-	defaults := getDefaultConfig()
+// mergeConfig layers overlay on top of base, per context and per command.
+// Commands listed in an overlay context's Remove are dropped from base first.
+// A command redefined by the overlay loses its inherited description unless
+// the overlay provides a new one, so descriptions never go stale.
+func mergeConfig(base, overlay *Config) {
+	if base.Contexts == nil {
+		base.Contexts = make(map[string]ContextConfig)
+	}
 
-	for ctxName, ctxCfg := range defaults.Contexts {
-		if _, exists := cfg.Contexts[ctxName]; !exists {
-			if cfg.Contexts == nil {
-				cfg.Contexts = make(map[string]ContextConfig)
+	for ctxName, over := range overlay.Contexts {
+		merged := ContextConfig{
+			Commands:     make(map[string]Command),
+			Descriptions: make(map[string]string),
+		}
+
+		if under, exists := base.Contexts[ctxName]; exists {
+			merged.Hooks = under.Hooks
+			merged.Detect = under.Detect
+			for name, cmd := range under.Commands {
+				merged.Commands[name] = cmd
+			}
+			for name, desc := range under.Descriptions {
+				merged.Descriptions[name] = desc
 			}
-			cfg.Contexts[ctxName] = ctxCfg
 		}
+
+		for _, name := range over.Remove {
+			delete(merged.Commands, name)
+			delete(merged.Descriptions, name)
+		}
+
+		for name, cmd := range over.Commands {
+			merged.Commands[name] = cmd
+			delete(merged.Descriptions, name)
+		}
+		for name, desc := range over.Descriptions {
+			merged.Descriptions[name] = desc
+		}
+
+		// Each hook list set in a later layer replaces the inherited one
+		if over.Before != nil {
+			merged.Before = over.Before
+		}
+		if over.After != nil {
+			merged.After = over.After
+		}
+		if over.OnFailure != nil {
+			merged.OnFailure = over.OnFailure
+		}
+
+		// Detection set in a later layer replaces the inherited one
+		if !over.Detect.IsEmpty() {
+			merged.Detect = over.Detect
+		}
+
+		base.Contexts[ctxName] = merged
 	}
 }
 
@@ -272,32 +625,15 @@ func mergeDefaults(cfg *Config) {
 func getDefaultConfig() *Config {
 	return &Config{
 		Contexts: map[string]ContextConfig{
-			"node": {
-				Commands: map[string]string{
-					"build":   "npm run build",
-					"test":    "npm test",
-					"start":   "npm start",
-					"dev":     "npm run dev",
-					"lint":    "npm run lint",
-					"install": "npm install",
-				},
-				Descriptions: map[string]string{
-					"build":   "Build the project (npm run build)",
-					"test":    "Run tests (npm test)",
-					"start":   "Start the application (npm start)",
-					"dev":     "Start development server (npm run dev)",
-					"lint":    "Run linter (npm run lint)",
-					"install": "Install dependencies (npm install)",
-				},
-			},
+			nodeContext: nodeCommands("npm"),
 			"go": {
-				Commands: map[string]string{
-					"build":   "go build ./...",
-					"test":    "go test ./...",
-					"run":     "go run ./cmd/...",
-					"install": "go mod download",
-					"lint":    "golangci-lint run",
-					"fmt":     "go fmt ./...",
+				Commands: map[string]Command{
+					"build":   {Run: "go build ./..."},
+					"test":    {Run: "go test ./..."},
+					"run":     {Run: "go run ./cmd/..."},
+					"install": {Run: "go mod download"},
+					"lint":    {Run: "golangci-lint run"},
+					"fmt":     {Run: "go fmt ./..."},
 				},
 				Descriptions: map[string]string{
 					"build":   "Build all packages (go build ./...)",
@@ -309,12 +645,12 @@ func getDefaultConfig() *Config {
 				},
 			},
 			"python": {
-				Commands: map[string]string{
-					"test":    "pytest",
-					"lint":    "ruff check .",
-					"fmt":     "black .",
-					"install": "pip install -r requirements.txt",
-					"run":     "python main.py",
+				Commands: map[string]Command{
+					"test":    {Run: "pytest"},
+					"lint":    {Run: "ruff check ."},
+					"fmt":     {Run: "black ."},
+					"install": {Run: "pip install -r requirements.txt"},
+					"run":     {Run: "python main.py"},
 				},
 				Descriptions: map[string]string{
 					"test":    "Run tests with pytest",
@@ -325,13 +661,13 @@ func getDefaultConfig() *Config {
 				},
 			},
 			"rust": {
-				Commands: map[string]string{
-					"build":   "cargo build",
-					"test":    "cargo test",
-					"run":     "cargo run",
-					"install": "cargo fetch",
-					"lint":    "cargo clippy",
-					"fmt":     "cargo fmt",
+				Commands: map[string]Command{
+					"build":   {Run: "cargo build"},
+					"test":    {Run: "cargo test"},
+					"run":     {Run: "cargo run"},
+					"install": {Run: "cargo fetch"},
+					"lint":    {Run: "cargo clippy"},
+					"fmt":     {Run: "cargo fmt"},
 				},
 				Descriptions: map[string]string{
 					"build":   "Build the project (cargo build)",
@@ -343,10 +679,10 @@ func getDefaultConfig() *Config {
 				},
 			},
 			"make": {
-				Commands: map[string]string{
-					"build": "make",
-					"test":  "make test",
-					"clean": "make clean",
+				Commands: map[string]Command{
+					"build": {Run: "make"},
+					"test":  {Run: "make test"},
+					"clean": {Run: "make clean"},
 				},
 				Descriptions: map[string]string{
 					"build": "Build using Makefile (make)",
@@ -354,6 +690,55 @@ func getDefaultConfig() *Config {
 					"clean": "Clean build artifacts (make clean)",
 				},
 			},
+			// Commands of the just and task contexts come from the
+			// justfile and Taskfile
+			justContext: {Commands: map[string]Command{}},
+			taskContext: {Commands: map[string]Command{}},
+			// A Gradle build or a Maven wrapper replaces these commands
+			javaContext: {
+				Commands: map[string]Command{
+					"build": {Run: "mvn package"},
+					"test":  {Run: "mvn test"},
+					"run":   {Run: "mvn exec:java"},
+					"clean": {Run: "mvn clean"},
+				},
+				Descriptions: map[string]string{
+					"build": "Build the project (mvn package)",
+					"test":  "Run tests (mvn test)",
+					"run":   "Run the application (mvn exec:java)",
+					"clean": "Clean build output (mvn clean)",
+				},
+			},
+			"ruby": {
+				Commands: map[string]Command{
+					"install": {Run: "bundle install"},
+					"test":    {Run: "bundle exec rake test"},
+					"build":   {Run: "bundle exec rake build"},
+					"lint":    {Run: "bundle exec rubocop"},
+					"fmt":     {Run: "bundle exec rubocop -a"},
+				},
+				Descriptions: map[string]string{
+					"install": "Install gems (bundle install)",
+					"test":    "Run tests (bundle exec rake test)",
+					"build":   "Build the gem (bundle exec rake build)",
+					"lint":    "Check code with RuboCop (bundle exec rubocop)",
+					"fmt":     "Fix offenses with RuboCop (bundle exec rubocop -a)",
+				},
+			},
+			"php": {
+				Commands: map[string]Command{
+					"install": {Run: "composer install"},
+					"update":  {Run: "composer update"},
+					"test":    {Run: "vendor/bin/phpunit"},
+					"lint":    {Run: "composer validate --strict"},
+				},
+				Descriptions: map[string]string{
+					"install": "Install dependencies (composer install)",
+					"update":  "Update dependencies (composer update)",
+					"test":    "Run tests with PHPUnit (vendor/bin/phpunit)",
+					"lint":    "Validate composer.json (composer validate --strict)",
+				},
+			},
 		},
 	}
 }
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
 )
 
 // TestValidateConfigPath tests path validation security
@@ -81,8 +84,16 @@ func TestValidateConfigPath(t *testing.T) {
 	}
 }
 
-// TestLoadFromFile_SizeLimit tests file size enforcement
-func TestLoadFromFile_SizeLimit(t *testing.T) {
+// loadTestProject loads the configuration of the project in dir without the
+// user's global config
+func loadTestProject(t *testing.T, dir string) (*Config, error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return LoadDir("", dir)
+}
+
+// TestLoadDir_SizeLimit tests file size enforcement
+func TestLoadDir_SizeLimit(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	tests := []struct {
@@ -110,7 +121,7 @@ func TestLoadFromFile_SizeLimit(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create test file with specific size
-			testFile := filepath.Join(tmpDir, "test.yaml")
+			testFile := filepath.Join(tmpDir, ProjectConfigName)
 
 			// Create valid YAML content
 			validYAML := "contexts:\n  test:\n    commands:\n      build: echo test\n"
@@ -130,27 +141,27 @@ func TestLoadFromFile_SizeLimit(t *testing.T) {
 				t.Fatalf("failed to create test file: %v", err)
 			}
 
-			_, err := loadFromFile(testFile)
+			_, err := loadTestProject(t, tmpDir)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("loadFromFile() expected error for file size %d, got nil", tt.fileSize)
+					t.Errorf("LoadDir() expected error for file size %d, got nil", tt.fileSize)
 				} else if !strings.Contains(err.Error(), "exceeds maximum size") {
-					t.Errorf("loadFromFile() expected size limit error, got: %v", err)
+					t.Errorf("LoadDir() expected size limit error, got: %v", err)
 				}
 			} else {
 				// Note: May fail due to malformed YAML from padding, that's OK for this test
 				// We're primarily testing size enforcement
 				if err != nil && strings.Contains(err.Error(), "exceeds maximum size") {
-					t.Errorf("loadFromFile() unexpected size limit error: %v", err)
+					t.Errorf("LoadDir() unexpected size limit error: %v", err)
 				}
 			}
 		})
 	}
 }
 
-// TestLoadFromFile_ValidConfig tests loading valid configurations
-func TestLoadFromFile_ValidConfig(t *testing.T) {
+// TestLoadDir_ValidConfig tests loading valid configurations
+func TestLoadDir_ValidConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	validConfig := `contexts:
@@ -161,14 +172,14 @@ func TestLoadFromFile_ValidConfig(t *testing.T) {
       deploy: ./deploy.sh
 `
 
-	testFile := filepath.Join(tmpDir, "valid.yaml")
+	testFile := filepath.Join(tmpDir, ProjectConfigName)
 	if err := os.WriteFile(testFile, []byte(validConfig), 0644); err != nil {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	cfg, err := loadFromFile(testFile)
+	cfg, err := loadTestProject(t, tmpDir)
 	if err != nil {
-		t.Fatalf("loadFromFile() unexpected error: %v", err)
+		t.Fatalf("LoadDir() unexpected error: %v", err)
 	}
 
 	// Verify custom context loaded
@@ -178,8 +189,8 @@ func TestLoadFromFile_ValidConfig(t *testing.T) {
 	}
 
 	// Verify commands
-	if customCtx.Commands["build"] != "make all" {
-		t.Errorf("expected build command 'make all', got %q", customCtx.Commands["build"])
+	if customCtx.Commands["build"].Run != "make all" {
+		t.Errorf("expected build command 'make all', got %q", customCtx.Commands["build"].Run)
 	}
 
 	// Verify defaults were merged
@@ -188,8 +199,396 @@ func TestLoadFromFile_ValidConfig(t *testing.T) {
 	}
 }
 
-// TestLoadFromFile_InvalidYAML tests YAML parsing security
-func TestLoadFromFile_InvalidYAML(t *testing.T) {
+// TestLoadDir_StructuredCommands tests the mapping form of command definitions
+func TestLoadDir_StructuredCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `contexts:
+  node:
+    commands:
+      build: npm run build
+      dev:
+        run: npm run dev
+        description: Start the dev server
+        timeout: none
+        env:
+          NODE_ENV: development
+      e2e:
+        run: npx playwright test
+        dir: web
+        timeout: 30m
+        confirm: true
+        args: ["--grep", "checkout flow"]
+      clean:
+        run: rm -rf dist && npm ci
+        shell: bash
+      open:
+        run: npx open-cli {{arg "url"}}
+        params: [url]
+`
+	testFile := filepath.Join(tmpDir, ProjectConfigName)
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cfg, err := loadTestProject(t, tmpDir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+
+	node := cfg.Contexts["node"]
+	if node.Commands["build"].Run != "npm run build" {
+		t.Errorf("build = %q, want plain string form to be kept", node.Commands["build"].Run)
+	}
+
+	dev := node.Commands["dev"]
+	if dev.Run != "npm run dev" {
+		t.Errorf("dev.Run = %q, want %q", dev.Run, "npm run dev")
+	}
+	if dev.Timeout == nil || *dev.Timeout != 0 {
+		t.Errorf("dev.Timeout = %v, want disabled timeout", dev.Timeout)
+	}
+	if dev.Env["NODE_ENV"] != "development" {
+		t.Errorf("dev.Env = %v, want NODE_ENV=development", dev.Env)
+	}
+	if node.Description("dev") != "Start the dev server" {
+		t.Errorf("Description(dev) = %q, want inline description", node.Description("dev"))
+	}
+
+	e2e := node.Commands["e2e"]
+	if e2e.Dir != "web" || !e2e.Confirm {
+		t.Errorf("e2e = %+v, want dir web and confirm", e2e)
+	}
+	if e2e.Timeout == nil || *e2e.Timeout != 30*time.Minute {
+		t.Errorf("e2e.Timeout = %v, want 30m", e2e.Timeout)
+	}
+	if len(e2e.Args) != 2 || e2e.Args[1] != "checkout flow" {
+		t.Errorf("e2e.Args = %q, want args kept as single elements", e2e.Args)
+	}
+	if got := e2e.String(); got != `npx playwright test --grep 'checkout flow'` {
+		t.Errorf("e2e.String() = %q", got)
+	}
+
+	if clean := node.Commands["clean"]; clean.Shell != "bash" {
+		t.Errorf("clean.Shell = %q, want %q", clean.Shell, "bash")
+	}
+	if open := node.Commands["open"]; len(open.Params) != 1 || open.Params[0] != "url" {
+		t.Errorf("open.Params = %q, want [url]", open.Params)
+	}
+}
+
+// TestLoadDir_CompositeCommands tests the list and mapping forms of composite commands
+func TestLoadDir_CompositeCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `contexts:
+  global:
+    commands:
+      clean-tmp: rm -rf tmp
+  go:
+    commands:
+      ci: [lint, test, build]
+      release:
+        steps: [ci, clean-tmp]
+        needs: [fmt]
+        continue_on_error: true
+        description: Full release pipeline
+      build:
+        run: go build ./...
+        needs: [generate]
+      generate: go generate ./...
+`
+	testFile := filepath.Join(tmpDir, ProjectConfigName)
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cfg, err := loadTestProject(t, tmpDir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+
+	goCtx := cfg.Contexts["go"]
+	ci := goCtx.Commands["ci"]
+	if !ci.IsComposite() || strings.Join(ci.Steps, ",") != "lint,test,build" {
+		t.Errorf("ci.Steps = %q, want [lint test build]", ci.Steps)
+	}
+	if got := ci.String(); got != "[lint, test, build]" {
+		t.Errorf("ci.String() = %q, want %q", got, "[lint, test, build]")
+	}
+
+	release := goCtx.Commands["release"]
+	if !release.ContinueOnError || len(release.Needs) != 1 || goCtx.Description("release") != "Full release pipeline" {
+		t.Errorf("release = %+v, want needs, continue_on_error and description", release)
+	}
+
+	// lint and test come from the built-in defaults, clean-tmp from global
+	if ctxName, _, ok := cfg.Resolve("go", "lint"); !ok || ctxName != "go" {
+		t.Errorf("Resolve(go, lint) = %q, %v, want go", ctxName, ok)
+	}
+	if ctxName, _, ok := cfg.Resolve("go", "clean-tmp"); !ok || ctxName != GlobalContext {
+		t.Errorf("Resolve(go, clean-tmp) = %q, %v, want global", ctxName, ok)
+	}
+	if _, _, ok := cfg.Resolve("node", "generate"); ok {
+		t.Error("Resolve(node, generate) found a command from another context")
+	}
+}
+
+// TestLoadDir_Hooks tests hooks at command and context level
+func TestLoadDir_Hooks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `contexts:
+  go:
+    before: echo starting
+    after:
+      - rm -rf tmp
+      - run: echo done
+        shell: sh
+    commands:
+      build:
+        run: go build ./...
+        before: [go generate ./...]
+        on_failure: echo build failed
+`
+	testFile := filepath.Join(tmpDir, ProjectConfigName)
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cfg, err := loadTestProject(t, tmpDir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+
+	goCtx := cfg.Contexts["go"]
+	if len(goCtx.Before) != 1 || goCtx.Before[0].Run != "echo starting" {
+		t.Errorf("context before = %+v, want [echo starting]", goCtx.Before)
+	}
+	if len(goCtx.After) != 2 || goCtx.After[1].Shell != "sh" {
+		t.Errorf("context after = %+v, want two hooks, the second run by sh", goCtx.After)
+	}
+
+	build := goCtx.Commands["build"]
+	if len(build.Before) != 1 || build.Before[0].Run != "go generate ./..." {
+		t.Errorf("build before = %+v, want [go generate ./...]", build.Before)
+	}
+	if len(build.OnFailure) != 1 || build.OnFailure[0].Run != "echo build failed" {
+		t.Errorf("build on_failure = %+v, want [echo build failed]", build.OnFailure)
+	}
+	if len(build.After) != 0 {
+		t.Errorf("build after = %+v, want none", build.After)
+	}
+}
+
+// TestLoadDir_Detect tests parsing detect sections
+func TestLoadDir_Detect(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `contexts:
+  nextjs:
+    detect:
+      priority: 10
+      markers: [next.config.js, next.config.mjs]
+      rules:
+        - file: package.json
+          key: dependencies.next
+    commands:
+      dev: next dev
+`
+	testFile := filepath.Join(tmpDir, ProjectConfigName)
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cfg, err := loadTestProject(t, tmpDir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+
+	detect := cfg.Contexts["nextjs"].Detect
+	if detect.Priority != 10 {
+		t.Errorf("priority = %d, want 10", detect.Priority)
+	}
+	rules := detect.DetectRules()
+	if len(rules) != 3 || rules[0].File != "next.config.js" || rules[2].Key != "dependencies.next" {
+		t.Errorf("DetectRules() = %+v, want the two markers and the key rule", rules)
+	}
+}
+
+// TestValidateReferences_AcrossLayers tests that cycles formed by merging layers are found
+func TestValidateReferences_AcrossLayers(t *testing.T) {
+	base := &Config{Contexts: map[string]ContextConfig{
+		"go": {Commands: map[string]Command{
+			"build": {Run: "go build", Needs: []string{"generate"}},
+		}},
+	}}
+	overlay := &Config{Contexts: map[string]ContextConfig{
+		"go": {Commands: map[string]Command{
+			"generate": {Run: "go generate", Needs: []string{"build"}},
+		}},
+	}}
+
+	if err := validateReferences(base, false); err != nil {
+		t.Fatalf("validateReferences(base) unexpected error: %v", err)
+	}
+	if err := validateReferences(base, true); err == nil {
+		t.Error("validateReferences(base, true) expected unknown command error, got nil")
+	}
+
+	mergeConfig(base, overlay)
+	err := validateReferences(base, true)
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle: build -> generate -> build") {
+		t.Errorf("validateReferences(merged) error = %v, want dependency cycle", err)
+	}
+}
+
+// TestLoadDir_InvalidCommandDefinition tests errors for malformed command mappings
+func TestLoadDir_InvalidCommandDefinition(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name:    "unknown field",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: go test\n        evn: {}\n",
+			errMsg:  `unknown command field "evn"`,
+		},
+		{
+			name:    "invalid timeout",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: go test\n        timeout: forever\n",
+			errMsg:  "invalid timeout",
+		},
+		{
+			name:    "steps are command names",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        - go test\n",
+			errMsg:  `invalid command reference "go test"`,
+		},
+		{
+			name:    "nested step list",
+			content: "contexts:\n  go:\n    commands:\n      ci:\n        - [lint, test]\n",
+			errMsg:  "steps must be command names",
+		},
+		{
+			name:    "composite with run",
+			content: "contexts:\n  go:\n    commands:\n      ci:\n        run: make ci\n        steps: [lint]\n",
+			errMsg:  "cannot set both run and steps",
+		},
+		{
+			name:    "composite with shell",
+			content: "contexts:\n  go:\n    commands:\n      ci:\n        steps: [lint]\n        shell: bash\n",
+			errMsg:  "composite command can only set",
+		},
+		{
+			name:    "unknown step",
+			content: "contexts:\n  go:\n    commands:\n      ci: [lint, tset]\n",
+			errMsg:  `context "go", command "ci": unknown command "tset"`,
+		},
+		{
+			name:    "step cycle",
+			content: "contexts:\n  go:\n    commands:\n      a: [b]\n      b: [c]\n      c: [a]\n",
+			errMsg:  `context "go": dependency cycle: a -> b -> c -> a`,
+		},
+		{
+			name:    "command needs itself",
+			content: "contexts:\n  go:\n    commands:\n      build:\n        run: go build\n        needs: [build]\n",
+			errMsg:  "dependency cycle: build -> build",
+		},
+		{
+			name:    "global commands cannot reference context commands",
+			content: "contexts:\n  global:\n    commands:\n      setup:\n        run: make setup\n        needs: [build]\n",
+			errMsg:  `context "global", command "setup": unknown command "build"`,
+		},
+		{
+			name:    "mapping without run",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        description: Run tests\n",
+			errMsg:  "empty command string",
+		},
+		{
+			name:    "invalid env name",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: go test\n        env:\n          \"A=B\": x\n",
+			errMsg:  "invalid environment variable name",
+		},
+		{
+			name:    "malformed template",
+			content: "contexts:\n  go:\n    commands:\n      build:\n        run: go build -o {{.ProjectName\n",
+			errMsg:  "unterminated template expression",
+		},
+		{
+			name:    "template syntax error",
+			content: "contexts:\n  go:\n    commands:\n      build:\n        run: go build -o {{if}}\n",
+			errMsg:  `invalid template "{{if}}"`,
+		},
+		{
+			name:    "unknown template function",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: echo hi\n        env:\n          TAG: \"{{ upper .Context }}\"\n",
+			errMsg:  `function "upper" not defined`,
+		},
+		{
+			name:    "template error in a script",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: echo {{ end }}\n        shell: sh\n",
+			errMsg:  "invalid template",
+		},
+		{
+			name:    "unsupported shell",
+			content: "contexts:\n  go:\n    commands:\n      test:\n        run: go test\n        shell: zsh\n",
+			errMsg:  `unsupported shell "zsh"`,
+		},
+		{
+			name:    "duplicate parameter",
+			content: "contexts:\n  k8s:\n    commands:\n      logs:\n        run: kubectl logs {{arg \"pod\"}}\n        params: [pod, pod]\n",
+			errMsg:  `duplicate parameter name "pod"`,
+		},
+		{
+			name:    "composite with params",
+			content: "contexts:\n  go:\n    commands:\n      ci:\n        steps: [lint]\n        params: [pkg]\n",
+			errMsg:  "composite command can only set",
+		},
+		{
+			name:    "hook with steps",
+			content: "contexts:\n  go:\n    commands:\n      build:\n        run: go build\n        before:\n          - steps: [lint]\n",
+			errMsg:  "before hook 1: hooks cannot have steps, needs, hooks or confirm",
+		},
+		{
+			name:    "hook list of lists",
+			content: "contexts:\n  go:\n    commands:\n      build:\n        run: go build\n        after:\n          - [lint]\n",
+			errMsg:  "hook must be a command, not a list of steps",
+		},
+		{
+			name:    "invalid context hook",
+			content: "contexts:\n  go:\n    on_failure:\n      run: echo failed\n      shell: zsh\n    commands:\n      build: go build\n",
+			errMsg:  `context "go": on_failure hook 1: unsupported shell "zsh"`,
+		},
+		{
+			name:    "unbalanced quote",
+			content: "contexts:\n  go:\n    commands:\n      test: go test -run 'TestFoo\n",
+			errMsg:  `context "go", command "test": invalid command line: unbalanced single quote`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, ProjectConfigName)
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+
+			_, err := loadTestProject(t, tmpDir)
+			if err == nil {
+				t.Fatal("LoadDir() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("LoadDir() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestLoadDir_InvalidYAML tests YAML parsing security
+func TestLoadDir_InvalidYAML(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	tests := []struct {
@@ -211,14 +610,14 @@ func TestLoadFromFile_InvalidYAML(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			testFile := filepath.Join(tmpDir, tt.name+".yaml")
+			testFile := filepath.Join(tmpDir, ProjectConfigName)
 			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
 				t.Fatalf("failed to create test file: %v", err)
 			}
 
-			_, err := loadFromFile(testFile)
+			_, err := loadTestProject(t, tmpDir)
 			if err == nil {
-				t.Errorf("loadFromFile() expected error for invalid YAML, got nil")
+				t.Errorf("LoadDir() expected error for invalid YAML, got nil")
 			}
 		})
 	}
@@ -237,8 +636,8 @@ func TestValidateConfig(t *testing.T) {
 			config: &Config{
 				Contexts: map[string]ContextConfig{
 					"test": {
-						Commands: map[string]string{
-							"build": "make",
+						Commands: map[string]Command{
+							"build": {Run: "make"},
 						},
 					},
 				},
@@ -259,7 +658,7 @@ func TestValidateConfig(t *testing.T) {
 				contexts := make(map[string]ContextConfig)
 				for i := 0; i < MaxContexts+1; i++ {
 					contexts[fmt.Sprintf("ctx%d", i)] = ContextConfig{
-						Commands: map[string]string{"test": "echo"},
+						Commands: map[string]Command{"test": {Run: "echo"}},
 					}
 				}
 				return &Config{Contexts: contexts}
@@ -270,9 +669,9 @@ func TestValidateConfig(t *testing.T) {
 		{
 			name: "too many commands in context",
 			config: func() *Config {
-				commands := make(map[string]string)
+				commands := make(map[string]Command)
 				for i := 0; i < MaxCommandsPerContext+1; i++ {
-					commands[fmt.Sprintf("cmd%d", i)] = "echo test"
+					commands[fmt.Sprintf("cmd%d", i)] = Command{Run: "echo test"}
 				}
 				return &Config{
 					Contexts: map[string]ContextConfig{
@@ -283,6 +682,71 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "too many commands",
 		},
+		{
+			name: "command both defined and removed",
+			config: &Config{
+				Contexts: map[string]ContextConfig{
+					"test": {
+						Commands: map[string]Command{"build": {Run: "make"}},
+						Remove:   []string{"build"},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "both defined and removed",
+		},
+		{
+			name:   "valid detect section",
+			config: detectConfig(DetectConfig{Markers: []string{"*.csproj", "src/*.sln"}, Priority: 5}),
+		},
+		{
+			name:    "marker with unsafe characters",
+			config:  detectConfig(DetectConfig{Markers: []string{"go.mod; rm -rf /"}}),
+			wantErr: true,
+			errMsg:  "marker contains invalid character",
+		},
+		{
+			name:    "marker outside the project",
+			config:  detectConfig(DetectConfig{Markers: []string{"../go.mod"}}),
+			wantErr: true,
+			errMsg:  "relative path",
+		},
+		{
+			name:    "invalid glob",
+			config:  detectConfig(DetectConfig{Markers: []string{"[.json"}}),
+			wantErr: true,
+			errMsg:  "invalid file pattern",
+		},
+		{
+			name:    "rule with unsafe file",
+			config:  detectConfig(DetectConfig{Rules: []contextpkg.Rule{{File: "$HOME/x.json", Key: "a"}}}),
+			wantErr: true,
+			errMsg:  "detection rule 1: invalid file",
+		},
+		{
+			name:    "rule with invalid regexp",
+			config:  detectConfig(DetectConfig{Rules: []contextpkg.Rule{{File: "go.mod", Match: "("}}}),
+			wantErr: true,
+			errMsg:  "invalid match",
+		},
+		{
+			name:    "priority out of range",
+			config:  detectConfig(DetectConfig{Priority: MaxDetectPriority + 1}),
+			wantErr: true,
+			errMsg:  "detection priority",
+		},
+		{
+			name: "too many markers",
+			config: func() *Config {
+				markers := make([]string, MaxDetectRules+1)
+				for i := range markers {
+					markers[i] = fmt.Sprintf("marker%d", i)
+				}
+				return detectConfig(DetectConfig{Markers: markers})
+			}(),
+			wantErr: true,
+			errMsg:  "too many detection markers",
+		},
 	}
 
 	for _, tt := range tests {
@@ -303,6 +767,18 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+// detectConfig returns a config with one context detected by detect
+func detectConfig(detect DetectConfig) *Config {
+	return &Config{
+		Contexts: map[string]ContextConfig{
+			"custom": {
+				Commands: map[string]Command{"build": {Run: "make"}},
+				Detect:   detect,
+			},
+		},
+	}
+}
+
 // TestValidateContextName tests context name validation
 func TestValidateContextName(t *testing.T) {
 	tests := []struct {
@@ -486,7 +962,7 @@ func TestLoad_DefaultConfig(t *testing.T) {
 	}
 
 	// Verify default contexts exist
-	expectedContexts := []string{"node", "go", "python", "rust", "make"}
+	expectedContexts := []string{"node", "go", "python", "rust", "make", "java", "ruby", "php", "just", "task"}
 	for _, ctx := range expectedContexts {
 		if _, exists := cfg.Contexts[ctx]; !exists {
 			t.Errorf("expected default context %q, not found", ctx)
@@ -494,6 +970,620 @@ func TestLoad_DefaultConfig(t *testing.T) {
 	}
 }
 
+// TestDefaultConfig_JavaRubyPHP tests the default commands of the java,
+// ruby and php contexts
+func TestDefaultConfig_JavaRubyPHP(t *testing.T) {
+	tests := []struct {
+		context string
+		command string
+		want    []string
+	}{
+		{"java", "build", []string{"mvn package"}},
+		{"java", "test", []string{"mvn test"}},
+		{"java", "run", []string{"mvn exec:java"}},
+		{"java", "clean", []string{"mvn clean"}},
+		{"ruby", "install", []string{"bundle install"}},
+		{"ruby", "test", []string{"bundle exec rake test"}},
+		{"ruby", "build", []string{"bundle exec rake build"}},
+		{"ruby", "lint", []string{"bundle exec rubocop"}},
+		{"ruby", "fmt", []string{"bundle exec rubocop -a"}},
+		{"php", "install", []string{"composer install"}},
+		{"php", "update", []string{"composer update"}},
+		{"php", "test", []string{"vendor/bin/phpunit"}},
+		{"php", "lint", []string{"composer validate"}},
+	}
+
+	cfg := getDefaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.context+" "+tt.command, func(t *testing.T) {
+			ctxConfig, exists := cfg.Contexts[tt.context]
+			if !exists {
+				t.Fatalf("expected default context %q", tt.context)
+			}
+			cmd, exists := ctxConfig.Commands[tt.command]
+			if !exists {
+				t.Fatalf("expected command %q in context %q", tt.command, tt.context)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(cmd.Run, want) {
+					t.Errorf("command %q = %q, want it to contain %q", tt.command, cmd.Run, want)
+				}
+			}
+			if ctxConfig.Description(tt.command) == "" {
+				t.Errorf("command %q has no description", tt.command)
+			}
+			if err := validateCommandDefinition(tt.command, cmd); err != nil {
+				t.Errorf("command %q is invalid: %v", tt.command, err)
+			}
+		})
+	}
+}
+
+// TestFindNodeProject tests that the package manager is taken from the
+// packageManager field or a lockfile, also in parents of a monorepo package
+func TestFindNodeProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		dir     string
+		wantPM  string
+		wantDir string
+	}{
+		{
+			name:    "no lockfile",
+			files:   map[string]string{"package.json": `{}`},
+			wantPM:  "npm",
+			wantDir: ".",
+		},
+		{
+			name:    "pnpm lockfile",
+			files:   map[string]string{"package.json": `{}`, "pnpm-lock.yaml": ""},
+			wantPM:  "pnpm",
+			wantDir: ".",
+		},
+		{
+			name:    "yarn lockfile",
+			files:   map[string]string{"package.json": `{}`, "yarn.lock": ""},
+			wantPM:  "yarn",
+			wantDir: ".",
+		},
+		{
+			name:    "bun lockfile",
+			files:   map[string]string{"package.json": `{}`, "bun.lockb": ""},
+			wantPM:  "bun",
+			wantDir: ".",
+		},
+		{
+			name:    "packageManager field wins over lockfile",
+			files:   map[string]string{"package.json": `{"packageManager": "yarn@4.1.0"}`, "package-lock.json": ""},
+			wantPM:  "yarn",
+			wantDir: ".",
+		},
+		{
+			name:    "unknown packageManager",
+			files:   map[string]string{"package.json": `{"packageManager": "pip@1.0"}`, "pnpm-lock.yaml": ""},
+			wantPM:  "pnpm",
+			wantDir: ".",
+		},
+		{
+			name: "lockfile at monorepo root",
+			files: map[string]string{
+				"package.json":              `{"private": true}`,
+				"pnpm-lock.yaml":            "",
+				"packages/web/package.json": `{}`,
+			},
+			dir:     "packages/web/src",
+			wantPM:  "pnpm",
+			wantDir: "packages/web",
+		},
+		{
+			name: "packageManager at monorepo root",
+			files: map[string]string{
+				"package.json":              `{"packageManager": "bun@1.1.0"}`,
+				"packages/web/package.json": `{}`,
+			},
+			dir:     "packages/web",
+			wantPM:  "bun",
+			wantDir: "packages/web",
+		},
+		{
+			name:  "invalid package.json",
+			files: map[string]string{"package.json": `{`, "yarn.lock": ""},
+		},
+		{
+			name:  "no package.json",
+			files: map[string]string{"yarn.lock": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+				t.Fatalf("failed to create .git: %v", err)
+			}
+			for name, content := range tt.files {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create directories: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+			dir := filepath.Join(root, filepath.FromSlash(tt.dir))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("failed to create directories: %v", err)
+			}
+
+			project, found := findNodeProject(dir)
+			if tt.wantDir == "" {
+				if found {
+					t.Fatalf("findNodeProject() found %+v, want none", project)
+				}
+				return
+			}
+			if !found {
+				t.Fatal("findNodeProject() found no project")
+			}
+			if want := filepath.Join(root, filepath.FromSlash(tt.wantDir)); project.Dir != want {
+				t.Errorf("Dir = %q, want %q", project.Dir, want)
+			}
+			if project.PackageManager != tt.wantPM {
+				t.Errorf("PackageManager = %q, want %q", project.PackageManager, tt.wantPM)
+			}
+		})
+	}
+}
+
+// TestLoadDir_NodeProject tests the node commands of a project with a lockfile
+// and package.json scripts, under the config files
+func TestLoadDir_NodeProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{"scripts": {
+			"build": "vite build",
+			"test:e2e": "playwright test",
+			"install": "node scripts/postinstall.js",
+			"bad name": "echo",
+			"deploy": "npm run build &&\nwrangler deploy"
+		}}`,
+		"yarn.lock":       "",
+		ProjectConfigName: "contexts:\n  node:\n    commands:\n      deploy: ./deploy.sh\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg, err := LoadDir("", dir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+	node := cfg.Contexts["node"]
+
+	tests := []struct {
+		command  string
+		wantRun  string
+		wantDesc string
+	}{
+		{"build", "yarn run build", "vite build"},
+		{"test", "yarn test", "Run tests (yarn test)"},
+		{"test:e2e", "yarn run test:e2e", "playwright test"},
+		{"install", "yarn install", "Install dependencies (yarn install)"},
+		{"deploy", "./deploy.sh", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := node.Commands[tt.command].Run; got != tt.wantRun {
+				t.Errorf("run = %q, want %q", got, tt.wantRun)
+			}
+			if got := node.Description(tt.command); got != tt.wantDesc {
+				t.Errorf("description = %q, want %q", got, tt.wantDesc)
+			}
+		})
+	}
+
+	if _, exists := node.Commands["bad name"]; exists {
+		t.Error("script with an invalid name became a command")
+	}
+}
+
+// TestNodeCommands tests the default node commands of each package manager
+func TestNodeCommands(t *testing.T) {
+	tests := []struct {
+		pm        string
+		wantTest  string
+		wantBuild string
+	}{
+		{"npm", "npm test", "npm run build"},
+		{"pnpm", "pnpm test", "pnpm run build"},
+		{"yarn", "yarn test", "yarn run build"},
+		{"bun", "bun run test", "bun run build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pm, func(t *testing.T) {
+			ctx := nodeCommands(tt.pm)
+			if got := ctx.Commands["test"].Run; got != tt.wantTest {
+				t.Errorf("test = %q, want %q", got, tt.wantTest)
+			}
+			if got := ctx.Commands["build"].Run; got != tt.wantBuild {
+				t.Errorf("build = %q, want %q", got, tt.wantBuild)
+			}
+			for name := range ctx.Commands {
+				if ctx.Descriptions[name] == "" {
+					t.Errorf("command %q has no description", name)
+				}
+			}
+		})
+	}
+}
+
+// TestLoad_LayeredMerge tests that defaults, user, project and specified files are merged per command
+func TestLoad_LayeredMerge(t *testing.T) {
+	homeDir := t.TempDir()
+	projectDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	userConfig := `contexts:
+  go:
+    commands:
+      cover: go test -cover ./...
+      lint: staticcheck ./...
+  personal:
+    commands:
+      notes: vim NOTES.md
+`
+	if err := os.MkdirAll(filepath.Join(homeDir, ".toolbox"), 0755); err != nil {
+		t.Fatalf("failed to create user config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, ".toolbox", "config.yaml"), []byte(userConfig), 0644); err != nil {
+		t.Fatalf("failed to write user config: %v", err)
+	}
+
+	projectConfig := `contexts:
+  go:
+    commands:
+      test: go test -race ./...
+      gen: go generate ./...
+    descriptions:
+      gen: Regenerate code
+    remove:
+      - fmt
+`
+	if err := os.WriteFile(".toolbox.yaml", []byte(projectConfig), 0644); err != nil {
+		t.Fatalf("failed to write project config: %v", err)
+	}
+
+	ciConfig := `contexts:
+  go:
+    commands:
+      test: go test -race -count=1 ./...
+`
+	if err := os.WriteFile("ci.yaml", []byte(ciConfig), 0644); err != nil {
+		t.Fatalf("failed to write ci config: %v", err)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	goCtx := cfg.Contexts["go"]
+	wantCommands := map[string]string{
+		"build": "go build ./...",       // built-in default
+		"cover": "go test -cover ./...", // user file
+		"lint":  "staticcheck ./...",    // user file overrides default
+		"test":  "go test -race ./...",  // project file overrides default
+		"gen":   "go generate ./...",    // project file
+	}
+	for name, want := range wantCommands {
+		if got := goCtx.Commands[name].Run; got != want {
+			t.Errorf("go.%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, exists := goCtx.Commands["fmt"]; exists {
+		t.Error("expected 'fmt' to be removed by project config")
+	}
+	if _, exists := goCtx.Descriptions["fmt"]; exists {
+		t.Error("expected description of removed 'fmt' to be dropped")
+	}
+	if _, exists := goCtx.Descriptions["test"]; exists {
+		t.Error("expected stale default description of overridden 'test' to be dropped")
+	}
+	if goCtx.Descriptions["build"] == "" {
+		t.Error("expected default description of 'build' to be kept")
+	}
+	if goCtx.Descriptions["gen"] != "Regenerate code" {
+		t.Errorf("go.gen description = %q, want %q", goCtx.Descriptions["gen"], "Regenerate code")
+	}
+	if _, exists := cfg.Contexts["personal"]; !exists {
+		t.Error("expected user context 'personal' to coexist with project config")
+	}
+	if _, exists := cfg.Contexts["node"]; !exists {
+		t.Error("expected default 'node' context to be kept")
+	}
+
+	// The specified file is the highest-priority layer
+	cfg, err = Load("ci.yaml")
+	if err != nil {
+		t.Fatalf("Load(ci.yaml) unexpected error: %v", err)
+	}
+	if got := cfg.Contexts["go"].Commands["test"].Run; got != "go test -race -count=1 ./..." {
+		t.Errorf("go.test with --config = %q, want specified file to win", got)
+	}
+	if got := cfg.Contexts["go"].Commands["gen"].Run; got != "go generate ./..." {
+		t.Errorf("go.gen with --config = %q, want project layer to be kept", got)
+	}
+}
+
+// TestFindProjectConfig tests upward discovery of .toolbox.yaml
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "internal", "foo")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+
+	// A config above the repository root must never be picked up
+	if err := os.WriteFile(filepath.Join(root, ProjectConfigName), []byte("contexts: {}"), 0644); err != nil {
+		t.Fatalf("failed to write outer config: %v", err)
+	}
+
+	if path, found := FindProjectConfig(nested); found {
+		t.Errorf("FindProjectConfig() found %q outside the repository", path)
+	}
+
+	repoConfig := filepath.Join(repo, ProjectConfigName)
+	if err := os.WriteFile(repoConfig, []byte("contexts: {}"), 0644); err != nil {
+		t.Fatalf("failed to write repo config: %v", err)
+	}
+
+	path, found := FindProjectConfig(nested)
+	if !found {
+		t.Fatal("FindProjectConfig() did not find config at repository root")
+	}
+	if path != repoConfig {
+		t.Errorf("FindProjectConfig() = %q, want %q", path, repoConfig)
+	}
+
+	// Without a .git boundary the search continues to the filesystem root
+	outside := filepath.Join(root, "plain", "sub")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	path, found = FindProjectConfig(outside)
+	if !found || path != filepath.Join(root, ProjectConfigName) {
+		t.Errorf("FindProjectConfig() = %q, %v, want outer config", path, found)
+	}
+}
+
+// TestLoad_ProjectConfigFromSubdirectory tests that Load finds the project config from a subdirectory
+func TestLoad_ProjectConfigFromSubdirectory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	nested := filepath.Join(repo, "internal", "foo")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	projectConfig := "contexts:\n  go:\n    commands:\n      gen: go generate ./...\n"
+	if err := os.WriteFile(filepath.Join(repo, ProjectConfigName), []byte(projectConfig), 0644); err != nil {
+		t.Fatalf("failed to write project config: %v", err)
+	}
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(nested); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if cfg.Contexts["go"].Commands["gen"].Run != "go generate ./..." {
+		t.Error("expected project config from repository root to be loaded")
+	}
+	if len(cfg.Sources) != 1 || filepath.Base(cfg.Sources[0]) != ProjectConfigName {
+		t.Errorf("Sources = %v, want the project config", cfg.Sources)
+	}
+}
+
+// TestLoadDir tests that the project layer is found from the given directory
+// rather than the working directory
+func TestLoadDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	api := filepath.Join(repo, "services", "api")
+	if err := os.MkdirAll(api, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	rootConfig := "contexts:\n  go:\n    commands:\n      gen: go generate ./...\n"
+	if err := os.WriteFile(filepath.Join(repo, ProjectConfigName), []byte(rootConfig), 0644); err != nil {
+		t.Fatalf("failed to write project config: %v", err)
+	}
+	apiConfig := "contexts:\n  go:\n    commands:\n      gen: buf generate\n"
+	if err := os.WriteFile(filepath.Join(api, ProjectConfigName), []byte(apiConfig), 0644); err != nil {
+		t.Fatalf("failed to write project config: %v", err)
+	}
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{".", "go generate ./..."},
+		{"services", "go generate ./..."},
+		{api, "buf generate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			cfg, err := LoadDir("", tt.dir)
+			if err != nil {
+				t.Fatalf("LoadDir() unexpected error: %v", err)
+			}
+			if got := cfg.Contexts["go"].Commands["gen"].Run; got != tt.want {
+				t.Errorf("gen = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadDir_RemovedDefaults tests that a default the project files remove
+// stays when a config file defines it or refers to it by steps or needs
+func TestLoadDir_RemovedDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	goProject := map[string]string{
+		"go.mod":        "module example.com/app\n\ngo 1.21\n",
+		"cmd/a/main.go": "package main\n\nfunc main() {}\n",
+		"cmd/b/main.go": "package main\n\nfunc main() {}\n",
+	}
+	makeProject := map[string]string{"Makefile": "all:\n\tcc main.c\n"}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		config  string
+		context string
+		want    map[string]string
+	}{
+		{
+			name:    "removed without references",
+			files:   goProject,
+			context: "go",
+			want:    map[string]string{"run": ""},
+		},
+		{
+			name:    "needs keeps the default",
+			files:   goProject,
+			config:  "contexts:\n  go:\n    commands:\n      dev:\n        run: air\n        needs: [run]\n",
+			context: "go",
+			want:    map[string]string{"run": "go run ./cmd/...", "dev": "air"},
+		},
+		{
+			name:    "config file defines it again",
+			files:   goProject,
+			config:  "contexts:\n  go:\n    commands:\n      run: go run ./cmd/a\n",
+			context: "go",
+			want:    map[string]string{"run": "go run ./cmd/a"},
+		},
+		{
+			name:    "steps keep the default",
+			files:   makeProject,
+			config:  "contexts:\n  make:\n    commands:\n      ci: [build, test]\n",
+			context: "make",
+			want:    map[string]string{"build": "make", "test": "make test", "clean": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			if tt.config != "" {
+				writeFiles(t, dir, map[string]string{ProjectConfigName: tt.config})
+			}
+
+			cfg, err := LoadDir("", dir)
+			if err != nil {
+				t.Fatalf("LoadDir() unexpected error: %v", err)
+			}
+			commands := cfg.Contexts[tt.context].Commands
+			for name, want := range tt.want {
+				if got := commands[name].Run; got != want {
+					t.Errorf("%s run = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestMergeConfig tests merging of a single overlay onto a base config
+func TestMergeConfig(t *testing.T) {
+	base := &Config{
+		Contexts: map[string]ContextConfig{
+			"node": {
+				Commands:     map[string]Command{"build": {Run: "npm run build"}, "lint": {Run: "npm run lint"}},
+				Descriptions: map[string]string{"build": "Build", "lint": "Lint"},
+				Hooks: Hooks{
+					Before: HookList{{Run: "nvm use"}},
+					After:  HookList{{Run: "rm -rf tmp"}},
+				},
+				Detect: DetectConfig{Markers: []string{".nvmrc"}},
+			},
+		},
+	}
+	overlay := &Config{
+		Contexts: map[string]ContextConfig{
+			"node": {
+				Descriptions: map[string]string{"build": "Build for production"},
+				Remove:       []string{"lint"},
+				Hooks:        Hooks{After: HookList{}},
+			},
+			"extra": {
+				Commands: map[string]Command{"hello": {Run: "echo hello"}},
+			},
+		},
+	}
+
+	mergeConfig(base, overlay)
+
+	node := base.Contexts["node"]
+	if node.Commands["build"].Run != "npm run build" {
+		t.Errorf("build = %q, want inherited command", node.Commands["build"].Run)
+	}
+	if node.Descriptions["build"] != "Build for production" {
+		t.Errorf("build description = %q, want overlay description", node.Descriptions["build"])
+	}
+	if _, exists := node.Commands["lint"]; exists {
+		t.Error("expected 'lint' to be removed")
+	}
+	if len(node.Before) != 1 || node.Before[0].Run != "nvm use" {
+		t.Errorf("before hooks = %+v, want inherited hooks", node.Before)
+	}
+	if len(node.After) != 0 {
+		t.Errorf("after hooks = %+v, want them cleared by the overlay", node.After)
+	}
+	if len(node.Detect.Markers) != 1 || node.Detect.Markers[0] != ".nvmrc" {
+		t.Errorf("detect = %+v, want inherited detect section", node.Detect)
+	}
+	if base.Contexts["extra"].Commands["hello"].Run != "echo hello" {
+		t.Error("expected new context 'extra' to be added")
+	}
+}
+
 // TestFileExists tests the fileExists helper
 func TestFileExists(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -547,9 +1637,9 @@ func BenchmarkValidateConfig(b *testing.B) {
 	cfg := &Config{
 		Contexts: map[string]ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"build": "make",
-					"test":  "make test",
+				Commands: map[string]Command{
+					"build": {Run: "make"},
+					"test":  {Run: "make test"},
 				},
 			},
 		},
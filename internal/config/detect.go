@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// DetectConfig tells how a context defined in configuration is detected.
+// Without it, such a context can only be used with --context.
+type DetectConfig struct {
+	// Markers are file names or glob patterns, such as deno.json or
+	// *.csproj, whose presence identifies the context
+	Markers []string `yaml:"markers,omitempty"`
+
+	// Rules can also look inside files, at key paths of JSON, TOML and
+	// YAML files or for regular expressions
+	Rules []contextpkg.Rule `yaml:"rules,omitempty"`
+
+	// Priority decides between contexts detected with the same score,
+	// higher first. Built-in contexts and plugins have priority 0.
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// IsEmpty reports whether no detection is configured
+func (d DetectConfig) IsEmpty() bool {
+	return len(d.Markers) == 0 && len(d.Rules) == 0 && d.Priority == 0
+}
+
+// DetectRules returns the markers as rules, followed by the rules
+func (d DetectConfig) DetectRules() []contextpkg.Rule {
+	rules := make([]contextpkg.Rule, 0, len(d.Markers)+len(d.Rules))
+	for _, marker := range d.Markers {
+		rules = append(rules, contextpkg.Rule{File: marker})
+	}
+	return append(rules, d.Rules...)
+}
+
+// validateDetect checks the markers, rules and priority of a context
+func validateDetect(d DetectConfig) error {
+	if n := len(d.Markers) + len(d.Rules); n > MaxDetectRules {
+		return fmt.Errorf("too many detection markers and rules (max: %d, got: %d)", MaxDetectRules, n)
+	}
+
+	if d.Priority < -MaxDetectPriority || d.Priority > MaxDetectPriority {
+		return fmt.Errorf("detection priority must be between %d and %d, got %d",
+			-MaxDetectPriority, MaxDetectPriority, d.Priority)
+	}
+
+	for _, marker := range d.Markers {
+		if err := validateMarker(marker); err != nil {
+			return fmt.Errorf("invalid marker %q: %w", marker, err)
+		}
+		if err := (contextpkg.Rule{File: marker}).Validate(); err != nil {
+			return fmt.Errorf("invalid marker %q: %w", marker, err)
+		}
+	}
+	for i, rule := range d.Rules {
+		if err := validateMarker(rule.File); err != nil {
+			return fmt.Errorf("detection rule %d: invalid file %q: %w", i+1, rule.File, err)
+		}
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("detection rule %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// validateMarker ensures a marker file name or glob pattern is safe, in the
+// way validateContextName checks context names
+func validateMarker(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty marker")
+	}
+
+	if len(name) > 100 {
+		return fmt.Errorf("marker too long (max 100 characters)")
+	}
+
+	// Only allow file name characters, path separators and glob syntax
+	for _, r := range name {
+		if !isAlphaNumeric(r) && !strings.ContainsRune("-_./*?[]^!", r) {
+			return fmt.Errorf("marker contains invalid character %q", r)
+		}
+	}
+
+	return nil
+}
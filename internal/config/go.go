@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// goContext is the name of the Go context
+const goContext = "go"
+
+// goMainPackage matches the package clause of a main package
+var goMainPackage = regexp.MustCompile(`(?m)^package\s+main\b`)
+
+// GoModule describes the Go module, and the go.work workspace if any, that a
+// directory belongs to
+type GoModule struct {
+	// Dir is the directory of go.mod. It is empty when only a go.work
+	// workspace was found.
+	Dir string
+
+	// Path is the module path
+	Path string
+
+	// Go and Toolchain are the go and toolchain directives of go.mod, such
+	// as "1.21" and "go1.22.1"
+	Go        string
+	Toolchain string
+
+	// Work is the go.work file of the workspace, and Use the directories of
+	// its modules, relative to the workspace
+	Work string
+	Use  []string
+}
+
+// Requirement describes the Go version the module needs, such as
+// "go 1.21 (toolchain go1.22.1)"
+func (m *GoModule) Requirement() string {
+	if m.Go == "" {
+		return ""
+	}
+	req := "go " + m.Go
+	if m.Toolchain != "" {
+		req += fmt.Sprintf(" (toolchain %s)", m.Toolchain)
+	}
+	return req
+}
+
+// FindGoModule looks for go.mod or go.work in dir and as many parents as
+// context detection searches. Once a module is found, go.work is looked for
+// further up to the repository root, as the go command would, unless GOWORK
+// is set: off disables workspaces, and a path names the go.work file.
+func FindGoModule(dir string) (*GoModule, bool) {
+	mod := &GoModule{}
+	searchDir, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		if data, ok := readProjectFile(filepath.Join(searchDir, "go.mod")); ok {
+			mod.Dir = searchDir
+			directives := goDirectives(string(data))
+			mod.Path = first(directives["module"])
+			mod.Go = first(directives["go"])
+			mod.Toolchain = first(directives["toolchain"])
+			return true
+		}
+		return fileExists(filepath.Join(searchDir, "go.work"))
+	})
+	if !found {
+		return nil, false
+	}
+
+	mod.Work = findGoWork(searchDir)
+	if mod.Work != "" {
+		if data, ok := readProjectFile(mod.Work); ok {
+			mod.Use = goDirectives(string(data))["use"]
+		}
+	}
+	if mod.Dir == "" && mod.Work == "" {
+		// Only reached with GOWORK=off outside a module
+		return nil, false
+	}
+	return mod, true
+}
+
+// findGoWork returns the go.work file for a module in dir, or "" if it is
+// not part of a workspace
+func findGoWork(dir string) string {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "", "auto":
+	default:
+		if fileExists(gowork) {
+			return gowork
+		}
+		return ""
+	}
+
+	workDir, found := contextpkg.WalkRepository(dir, func(searchDir string) bool {
+		return fileExists(filepath.Join(searchDir, "go.work"))
+	})
+	if !found {
+		return ""
+	}
+	return filepath.Join(workDir, "go.work")
+}
+
+// goLayer returns the commands of the Go module or workspace dir belongs
+// to: build and test across every module of a go.work workspace, and
+// run:<name> and build:<name> for each main package in cmd/. Run starts the
+// only main package, and is removed when there is none or several. It is nil
+// outside a Go module.
+func goLayer(dir string) *Config {
+	mod, found := FindGoModule(dir)
+	if !found {
+		return nil
+	}
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+
+	// Commands run from the module; workspace-wide ones from the workspace
+	base := mod.Dir
+	var workDir string
+	if mod.Work != "" {
+		workDir = filepath.Dir(mod.Work)
+		if base == "" {
+			base = workDir
+		}
+	}
+
+	if workDir != "" && len(mod.Use) > 0 {
+		patterns := make([]string, len(mod.Use))
+		for i, use := range mod.Use {
+			patterns[i] = goPackagePattern(use) + "/..."
+		}
+		for _, step := range []struct{ name, verb, desc string }{
+			{"build", "build", "Build every workspace module"},
+			{"test", "test", "Test every workspace module"},
+		} {
+			run := "go " + step.verb + " " + strings.Join(patterns, " ")
+			ctx.Commands[step.name] = Command{Run: run, Dir: workDir}
+			ctx.Descriptions[step.name] = fmt.Sprintf("%s (%s)", step.desc, run)
+		}
+	}
+
+	// Without go.mod here, the main packages of every workspace module
+	roots := []string{mod.Dir}
+	if mod.Dir == "" {
+		roots = nil
+		for _, use := range mod.Use {
+			root := filepath.FromSlash(use)
+			if !filepath.IsAbs(root) {
+				root = filepath.Join(workDir, root)
+			}
+			roots = append(roots, root)
+		}
+	}
+
+	var mains []string
+	for _, root := range roots {
+		for _, name := range goMainPackages(root) {
+			rel, err := filepath.Rel(base, filepath.Join(root, "cmd", name))
+			if err != nil {
+				continue
+			}
+			pkg := goPackagePattern(filepath.ToSlash(rel))
+			if _, exists := ctx.Commands["run:"+name]; exists || !isCommandName("run:"+name) {
+				continue
+			}
+			mains = append(mains, name)
+
+			run := Command{Run: "go run " + pkg}
+			build := Command{Run: "go build " + pkg}
+			if base != mod.Dir {
+				run.Dir, build.Dir = base, base
+			}
+			ctx.Commands["run:"+name] = run
+			ctx.Commands["build:"+name] = build
+			ctx.Descriptions["run:"+name] = fmt.Sprintf("Run %s (%s)", name, run.Run)
+			ctx.Descriptions["build:"+name] = fmt.Sprintf("Build %s (%s)", name, build.Run)
+		}
+	}
+
+	switch {
+	case len(mains) == 1:
+		run := ctx.Commands["run:"+mains[0]]
+		ctx.Commands["run"] = run
+		ctx.Descriptions["run"] = fmt.Sprintf("Run main program (%s)", run.Run)
+	case len(mains) == 0 && mod.Dir != "" && isGoMainPackage(mod.Dir):
+		ctx.Commands["run"] = Command{Run: "go run ."}
+		ctx.Descriptions["run"] = "Run main program (go run .)"
+	default:
+		ctx.Remove = []string{"run"}
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{goContext: ctx}}
+}
+
+// goMainPackages returns the names of the directories of cmd/ in a module
+// that hold a main package, sorted
+func goMainPackages(moduleDir string) []string {
+	entries, err := os.ReadDir(filepath.Join(moduleDir, "cmd"))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && isGoMainPackage(filepath.Join(moduleDir, "cmd", entry.Name())) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isGoMainPackage reports whether a non-test Go file in dir declares
+// package main
+func isGoMainPackage(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		if data, ok := readProjectFile(file); ok && goMainPackage.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// goPackagePattern turns a slash-separated directory into a package pattern
+// the go command accepts, such as "./cmd/tb" or ".". Absolute directories
+// and those outside the current one are already patterns.
+func goPackagePattern(dir string) string {
+	if filepath.IsAbs(dir) || strings.HasPrefix(dir, "../") {
+		return dir
+	}
+	dir = strings.TrimPrefix(dir, "./")
+	if dir == "" || dir == "." {
+		return "."
+	}
+	return "./" + dir
+}
+
+// goDirectives reads the directives of a go.mod or go.work file, such as
+// module, go and use, with the arguments of each line. Blocks such as
+// use ( ... ) give one entry per line. Comments are ignored and quoted
+// arguments unquoted.
+func goDirectives(data string) map[string][]string {
+	directives := make(map[string][]string)
+	block := ""
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			directives[block] = append(directives[block], strings.Trim(fields[0], "\"`"))
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if len(fields) >= 2 {
+			directives[fields[0]] = append(directives[fields[0]], strings.Trim(fields[1], "\"`"))
+		}
+	}
+	return directives
+}
+
+// first returns the first element of list, or ""
+func first(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestGoLayer tests the run, build and test commands generated for Go
+// modules and workspaces
+func TestGoLayer(t *testing.T) {
+	const mainFile = "package main\n\nfunc main() {}\n"
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		// dir is where the search starts, relative to the root
+		dir        string
+		want       map[string]string
+		wantDirs   map[string]string
+		wantRemove []string
+	}{
+		{
+			name: "several binaries",
+			files: map[string]string{
+				"go.mod":                  "module example.com/app\n\ngo 1.22\n",
+				"cmd/api/main.go":         mainFile,
+				"cmd/worker/main.go":      mainFile,
+				"cmd/worker/main_test.go": "package main\n",
+				"cmd/shared/shared.go":    "package shared\n",
+				"cmd/README.md":           "",
+			},
+			want: map[string]string{
+				"run:api":      "go run ./cmd/api",
+				"build:api":    "go build ./cmd/api",
+				"run:worker":   "go run ./cmd/worker",
+				"build:worker": "go build ./cmd/worker",
+			},
+			wantRemove: []string{"run"},
+		},
+		{
+			name: "one binary",
+			files: map[string]string{
+				"go.mod":         "module example.com/app\n",
+				"cmd/tb/main.go": mainFile,
+			},
+			want: map[string]string{
+				"run":      "go run ./cmd/tb",
+				"run:tb":   "go run ./cmd/tb",
+				"build:tb": "go build ./cmd/tb",
+			},
+		},
+		{
+			name: "main package at the module root",
+			files: map[string]string{
+				"go.mod":  "module example.com/app\n",
+				"main.go": mainFile,
+			},
+			want: map[string]string{"run": "go run ."},
+		},
+		{
+			name: "library",
+			files: map[string]string{
+				"go.mod": "module example.com/lib\n",
+				"lib.go": "package lib\n",
+			},
+			want:       map[string]string{},
+			wantRemove: []string{"run"},
+		},
+		{
+			name: "module in a workspace",
+			files: map[string]string{
+				"go.work":                 "go 1.22\n\nuse (\n\t./api\n\t./lib // shared code\n)\n",
+				"api/go.mod":              "module example.com/api\n",
+				"api/cmd/server/main.go":  mainFile,
+				"lib/go.mod":              "module example.com/lib\n",
+				"lib/cmd/migrate/main.go": mainFile,
+			},
+			dir: "api",
+			want: map[string]string{
+				"build":        "go build ./api/... ./lib/...",
+				"test":         "go test ./api/... ./lib/...",
+				"run":          "go run ./cmd/server",
+				"run:server":   "go run ./cmd/server",
+				"build:server": "go build ./cmd/server",
+			},
+			wantDirs: map[string]string{"build": ".", "test": "."},
+		},
+		{
+			name: "workspace root",
+			files: map[string]string{
+				"go.work":                 "go 1.22\n\nuse ./api\nuse ./lib\n",
+				"api/go.mod":              "module example.com/api\n",
+				"api/cmd/server/main.go":  mainFile,
+				"lib/go.mod":              "module example.com/lib\n",
+				"lib/cmd/migrate/main.go": mainFile,
+			},
+			want: map[string]string{
+				"build":         "go build ./api/... ./lib/...",
+				"test":          "go test ./api/... ./lib/...",
+				"run:server":    "go run ./api/cmd/server",
+				"build:server":  "go build ./api/cmd/server",
+				"run:migrate":   "go run ./lib/cmd/migrate",
+				"build:migrate": "go build ./lib/cmd/migrate",
+			},
+			wantDirs: map[string]string{
+				"build": ".", "test": ".",
+				"run:server": ".", "build:server": ".", "run:migrate": ".", "build:migrate": ".",
+			},
+			wantRemove: []string{"run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOWORK", "")
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			layer := goLayer(filepath.Join(root, filepath.FromSlash(tt.dir)))
+			if layer == nil {
+				t.Fatal("goLayer() = nil, want a layer")
+			}
+			ctx := layer.Contexts["go"]
+
+			got := make(map[string]string)
+			for name, cmd := range ctx.Commands {
+				got[name] = cmd.Run
+				if ctx.Description(name) == "" {
+					t.Errorf("command %q has no description", name)
+				}
+
+				wantDir := ""
+				if dir, ok := tt.wantDirs[name]; ok {
+					wantDir = filepath.Join(root, dir)
+				}
+				if cmd.Dir != wantDir {
+					t.Errorf("command %q dir = %q, want %q", name, cmd.Dir, wantDir)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(ctx.Remove, tt.wantRemove) {
+				t.Errorf("Remove = %v, want %v", ctx.Remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+// TestFindGoModule tests reading go.mod and go.work
+func TestFindGoModule(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":       "",
+		"go.work":         "go 1.22\n\nuse (\n\t.\n\t\"./tools\"\n)\n",
+		"go.mod":          "// The app\nmodule \"example.com/app\"\n\ngo 1.21.5\n\ntoolchain go1.22.1\n\nrequire (\n\tgolang.org/x/sys v0.20.0\n)\n",
+		"tools/go.mod":    "module example.com/tools\n",
+		"internal/a/a.go": "package a\n",
+	})
+
+	tests := []struct {
+		name   string
+		gowork string
+		want   GoModule
+	}{
+		{
+			name: "workspace",
+			want: GoModule{
+				Dir:       root,
+				Path:      "example.com/app",
+				Go:        "1.21.5",
+				Toolchain: "go1.22.1",
+				Work:      filepath.Join(root, "go.work"),
+				Use:       []string{".", "./tools"},
+			},
+		},
+		{
+			name:   "GOWORK=off",
+			gowork: "off",
+			want: GoModule{
+				Dir:       root,
+				Path:      "example.com/app",
+				Go:        "1.21.5",
+				Toolchain: "go1.22.1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOWORK", tt.gowork)
+
+			mod, found := FindGoModule(filepath.Join(root, "internal", "a"))
+			if !found {
+				t.Fatal("FindGoModule() found no module")
+			}
+			if !reflect.DeepEqual(*mod, tt.want) {
+				t.Errorf("FindGoModule() = %+v, want %+v", *mod, tt.want)
+			}
+			if got, want := mod.Requirement(), "go 1.21.5 (toolchain go1.22.1)"; got != want {
+				t.Errorf("Requirement() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// TestFindGoModule_NotFound tests that directories outside a module have none
+func TestFindGoModule_NotFound(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n"})
+
+	if mod, found := FindGoModule(dir); found {
+		t.Errorf("FindGoModule() = %+v, want none", mod)
+	}
+}
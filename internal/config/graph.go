@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GlobalContext is the context whose commands can be referenced as steps or
+// needs from every other context
+const GlobalContext = "global"
+
+// Resolve looks up a command referenced from ctxName: first in ctxName
+// itself, then in the global context. It returns the context the command
+// was found in.
+func (c *Config) Resolve(ctxName, name string) (string, Command, bool) {
+	if c == nil {
+		return "", Command{}, false
+	}
+
+	if cmd, ok := c.Contexts[ctxName].Commands[name]; ok {
+		return ctxName, cmd, true
+	}
+	if cmd, ok := c.Contexts[GlobalContext].Commands[name]; ok {
+		return GlobalContext, cmd, true
+	}
+
+	return "", Command{}, false
+}
+
+// References returns the commands a command depends on: its steps followed
+// by its needs
+func (c Command) References() []string {
+	refs := make([]string, 0, len(c.Steps)+len(c.Needs))
+	refs = append(refs, c.Steps...)
+	return append(refs, c.Needs...)
+}
+
+// referencedCommands returns the commands that another command refers to
+// by steps or needs, as "context/command"
+func referencedCommands(cfg *Config) map[string]bool {
+	referenced := make(map[string]bool)
+	for ctxName, ctx := range cfg.Contexts {
+		for _, cmd := range ctx.Commands {
+			for _, ref := range cmd.References() {
+				if refCtx, _, ok := cfg.Resolve(ctxName, ref); ok {
+					referenced[refCtx+"/"+ref] = true
+				}
+			}
+		}
+	}
+	return referenced
+}
+
+// validateReferences checks the step and needs graph of cfg for cycles.
+// When requireResolved is set, every reference must also name an existing
+// command; a single file may reference commands from other layers, so this
+// is only enforced once all layers have been merged.
+func validateReferences(cfg *Config, requireResolved bool) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var rootCtx string
+
+	var visit func(ctxName, name string, path []string) error
+	visit = func(ctxName, name string, path []string) error {
+		// Commands from other contexts are shown qualified in the cycle
+		label := name
+		if ctxName != rootCtx {
+			label = ctxName + ":" + name
+		}
+
+		key := ctxName + "/" + name
+		switch state[key] {
+		case visiting:
+			return fmt.Errorf("context %q: dependency cycle: %s", rootCtx, strings.Join(append(path, label), " -> "))
+		case done:
+			return nil
+		}
+
+		state[key] = visiting
+		for _, ref := range cfg.Contexts[ctxName].Commands[name].References() {
+			refCtx, _, ok := cfg.Resolve(ctxName, ref)
+			if !ok {
+				if requireResolved {
+					return fmt.Errorf("context %q, command %q: unknown command %q", ctxName, name, ref)
+				}
+				continue
+			}
+			if err := visit(refCtx, ref, append(path, label)); err != nil {
+				return err
+			}
+		}
+		state[key] = done
+
+		return nil
+	}
+
+	// Visit in a fixed order so the reported cycle is stable
+	for _, ctxName := range sortedKeys(cfg.Contexts) {
+		rootCtx = ctxName
+		names := make([]string, 0, len(cfg.Contexts[ctxName].Commands))
+		for name := range cfg.Contexts[ctxName].Commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := visit(ctxName, name, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns the context names of contexts in sorted order
+func sortedKeys(contexts map[string]ContextConfig) []string {
+	keys := make([]string, 0, len(contexts))
+	for key := range contexts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// javaContext is the name of the Java context
+const javaContext = "java"
+
+// gradleBuildFiles mark a Gradle build; a project with pom.xml and none of
+// them is built with Maven
+var gradleBuildFiles = []string{"build.gradle", "build.gradle.kts"}
+
+// javaLayer returns the commands of the Gradle or Maven project in dir or
+// the nearest parent. A Gradle build runs Gradle tasks and a Maven project
+// Maven goals, through the project's gradlew or mvnw wrapper when it has
+// one. It is nil when there is no build file.
+func javaLayer(dir string) *Config {
+	projectDir, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		return isGradleProject(searchDir) || fileExists(filepath.Join(searchDir, "pom.xml"))
+	})
+	if !found {
+		return nil
+	}
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+	add := func(name, run, desc string) {
+		ctx.Commands[name] = Command{Run: run}
+		ctx.Descriptions[name] = fmt.Sprintf("%s (%s)", desc, run)
+	}
+
+	if isGradleProject(projectDir) {
+		gradle := javaTool(projectDir, "gradle", "gradlew", "gradlew.bat")
+		add("build", gradle+" build", "Build the project")
+		add("test", gradle+" test", "Run tests")
+		add("run", gradle+" run", "Run the application")
+		add("clean", gradle+" clean", "Clean build output")
+	} else {
+		mvn := javaTool(projectDir, "mvn", "mvnw", "mvnw.cmd")
+		add("build", mvn+" package", "Build the project")
+		add("test", mvn+" test", "Run tests")
+		add("run", mvn+" exec:java", "Run the application")
+		add("clean", mvn+" clean", "Clean build output")
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{javaContext: ctx}}
+}
+
+// isGradleProject reports whether dir holds a Gradle build
+func isGradleProject(dir string) bool {
+	for _, name := range gradleBuildFiles {
+		if fileExists(filepath.Join(dir, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// javaTool returns the command that runs a build tool in the project in
+// dir: its wrapper script when the project has one, executable on Unix or
+// the batch file on Windows, and the tool on PATH otherwise
+func javaTool(dir, tool, wrapper, windowsWrapper string) string {
+	if runtime.GOOS == "windows" {
+		if fileExists(filepath.Join(dir, windowsWrapper)) {
+			return "./" + windowsWrapper
+		}
+		return tool
+	}
+
+	info, err := os.Stat(filepath.Join(dir, wrapper))
+	if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+		return "./" + wrapper
+	}
+	return tool
+}
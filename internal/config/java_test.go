@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// TestJavaLayer tests that the java commands pick Gradle or Maven and
+// their wrappers from the project files
+func TestJavaLayer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("wrappers are found by their executable bit")
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]os.FileMode
+		want  map[string]string
+	}{
+		{
+			name:  "maven",
+			files: map[string]os.FileMode{"pom.xml": 0644},
+			want:  map[string]string{"build": "mvn package", "test": "mvn test", "run": "mvn exec:java", "clean": "mvn clean"},
+		},
+		{
+			name:  "maven wrapper",
+			files: map[string]os.FileMode{"pom.xml": 0644, "mvnw": 0755},
+			want:  map[string]string{"build": "./mvnw package", "test": "./mvnw test", "run": "./mvnw exec:java", "clean": "./mvnw clean"},
+		},
+		{
+			name:  "gradle",
+			files: map[string]os.FileMode{"build.gradle": 0644},
+			want:  map[string]string{"build": "gradle build", "test": "gradle test", "run": "gradle run", "clean": "gradle clean"},
+		},
+		{
+			name:  "gradle kotlin dsl with wrapper",
+			files: map[string]os.FileMode{"build.gradle.kts": 0644, "gradlew": 0755},
+			want:  map[string]string{"build": "./gradlew build", "test": "./gradlew test", "run": "./gradlew run", "clean": "./gradlew clean"},
+		},
+		{
+			name:  "gradle wins over maven",
+			files: map[string]os.FileMode{"build.gradle": 0644, "pom.xml": 0644, "mvnw": 0755},
+			want:  map[string]string{"build": "gradle build", "test": "gradle test", "run": "gradle run", "clean": "gradle clean"},
+		},
+		{
+			name:  "wrapper must be executable",
+			files: map[string]os.FileMode{"pom.xml": 0644, "mvnw": 0644},
+			want:  map[string]string{"build": "mvn package", "test": "mvn test", "run": "mvn exec:java", "clean": "mvn clean"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, mode := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+					t.Fatalf("failed to create %s: %v", name, err)
+				}
+			}
+
+			layer := javaLayer(dir)
+			if layer == nil {
+				t.Fatal("javaLayer() = nil, want a layer")
+			}
+			ctx := layer.Contexts["java"]
+
+			got := make(map[string]string)
+			for name, cmd := range ctx.Commands {
+				got[name] = cmd.Run
+				if cmd.Shell != "" {
+					t.Errorf("%s runs in shell %q, want a plain command", name, cmd.Shell)
+				}
+				if desc := ctx.Description(name); desc == "" {
+					t.Errorf("%s has no description", name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestJavaLayer_NoProject tests that there is no layer without a build file
+func TestJavaLayer_NoProject(t *testing.T) {
+	if layer := javaLayer(t.TempDir()); layer != nil {
+		t.Errorf("javaLayer() = %+v, want nil", layer)
+	}
+}
+
+// TestLoadDir_JavaProject tests that the java commands of a subdirectory
+// come from the build file of the project above it
+func TestLoadDir_JavaProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"build.gradle":           "plugins { id 'application' }\n",
+		"src/main/java/App.java": "class App {}\n",
+	})
+
+	cfg, err := LoadDir("", filepath.Join(dir, "src", "main", "java"))
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+	ctx := cfg.Contexts["java"]
+	if got := ctx.Commands["test"].Run; got != "gradle test" {
+		t.Errorf("test run = %q, want %q", got, "gradle test")
+	}
+	if got := ctx.Description("test"); got != "Run tests (gradle test)" {
+		t.Errorf("test description = %q, want %q", got, "Run tests (gradle test)")
+	}
+}
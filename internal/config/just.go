@@ -0,0 +1,85 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// justContext is the name of the just context
+const justContext = "just"
+
+// justfileNames are the file names just reads
+var justfileNames = []string{"justfile", "Justfile", ".justfile"}
+
+// justRecipe matches a recipe line: an optional @, the name, then any
+// parameters before the colon. Assignments, aliases and settings (name :=
+// value) do not match.
+var justRecipe = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(?:\s+[^:]*)?:(?:[^=].*)?$`)
+
+// justDoc matches the [doc("...")] attribute
+var justDoc = regexp.MustCompile(`\bdoc\(\s*["']([^"']*)["']\s*\)`)
+
+// justLayer returns a command for every public recipe of the justfile in dir
+// or the nearest parent, described by its doc comment. Recipes starting with
+// an underscore or marked [private] are left out. It is nil when there is no
+// justfile.
+func justLayer(dir string) *Config {
+	path, found := findProjectFile(dir, justfileNames)
+	if !found {
+		return nil
+	}
+	data, ok := readProjectFile(path)
+	if !ok {
+		return nil
+	}
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+	for _, recipe := range justRecipes(string(data)) {
+		if !isCommandName(recipe.Name) {
+			continue
+		}
+		ctx.Commands[recipe.Name] = Command{Run: "just " + recipe.Name}
+		if recipe.Help != "" {
+			ctx.Descriptions[recipe.Name] = recipe.Help
+		}
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{justContext: ctx}}
+}
+
+// justRecipes returns the public recipes of a justfile with their doc
+// comments: the comment line right above the recipe, or a doc attribute
+func justRecipes(data string) []makeTarget {
+	var recipes []makeTarget
+	doc, private := "", false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "" || line[0] == ' ' || line[0] == '\t':
+			// Blank lines and recipe bodies end a doc comment
+			doc, private = "", false
+		case strings.HasPrefix(trimmed, "#!"):
+		case strings.HasPrefix(trimmed, "#"):
+			doc = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case strings.HasPrefix(trimmed, "["):
+			// Attributes sit between the doc comment and the recipe
+			if strings.Contains(trimmed, "private") {
+				private = true
+			}
+			if m := justDoc.FindStringSubmatch(trimmed); m != nil {
+				doc = m[1]
+			}
+		default:
+			if m := justRecipe.FindStringSubmatch(line); m != nil && !private && !strings.HasPrefix(m[1], "_") {
+				recipes = append(recipes, makeTarget{Name: m[1], Help: doc})
+			}
+			doc, private = "", false
+		}
+	}
+	return recipes
+}
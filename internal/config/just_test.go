@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestJustLayer tests the commands generated from justfile recipes
+func TestJustLayer(t *testing.T) {
+	justfile := `#!/usr/bin/env just
+set shell := ["bash", "-c"]
+version := "1.0"
+export RUST_LOG := "info"
+alias b := build
+
+# Build the project
+build:
+    cargo build
+
+# Run the tests
+[group('dev')]
+test *args: build
+    cargo test {{args}}
+
+[doc("Deploy to an environment")]
+deploy env="staging":
+    ./deploy.sh {{env}}
+
+@fmt:
+    cargo fmt
+
+[private]
+helper:
+    echo hidden
+
+_setup:
+    echo hidden
+
+# not attached
+
+release:
+    ./release.sh
+`
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"justfile": justfile})
+
+	layer := justLayer(dir)
+	if layer == nil {
+		t.Fatal("justLayer() = nil, want a layer")
+	}
+	ctx := layer.Contexts["just"]
+
+	want := map[string]string{
+		"build":   "Build the project",
+		"test":    "Run the tests",
+		"deploy":  "Deploy to an environment",
+		"fmt":     "",
+		"release": "",
+	}
+	got := make(map[string]string)
+	for name, cmd := range ctx.Commands {
+		if cmd.Run != "just "+name {
+			t.Errorf("command %q run = %q, want %q", name, cmd.Run, "just "+name)
+		}
+		got[name] = ctx.Description(name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recipes = %v, want %v", got, want)
+	}
+}
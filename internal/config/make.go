@@ -0,0 +1,228 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// makeContext is the name of the Make context
+const makeContext = "make"
+
+// makefileNames are the file names make reads, in the order it tries them
+var makefileNames = []string{"GNUmakefile", "makefile", "Makefile"}
+
+// maxMakeIncludes bounds how deeply included makefiles are followed
+const maxMakeIncludes = 3
+
+// makeRule matches a rule line without its comment. Variable assignments
+// (=, :=, ::=, ?=, +=, !=) do not match.
+var makeRule = regexp.MustCompile(`^[^\s:=?+!][^:=]*?\s*::?(?:[^:=].*)?$`)
+
+// makeTarget is a target of a makefile that can be run as a command
+type makeTarget struct {
+	Name string
+
+	// Help is the "## comment" of the target, if any
+	Help string
+}
+
+// makeLayer returns a command for every target of the makefile in dir or
+// the nearest parent, described by its "## comment". Pattern rules, special
+// targets such as .PHONY and file targets are left out. Build runs the
+// build target when there is one, and the default goal otherwise; test and
+// clean are removed when the makefile has no such target. It is nil when
+// there is no makefile.
+func makeLayer(dir string) *Config {
+	path, found := findProjectFile(dir, makefileNames)
+	if !found {
+		return nil
+	}
+	targets := makeTargets(path)
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+	defaults := getDefaultConfig().Contexts[makeContext]
+	for _, target := range targets {
+		run := "make " + target.Name
+
+		// Keep the default and its description for a plain test or clean
+		if def, ok := defaults.Commands[target.Name]; ok && def.Run == run && target.Help == "" {
+			continue
+		}
+		ctx.Commands[target.Name] = Command{Run: run}
+		if target.Help != "" {
+			ctx.Descriptions[target.Name] = target.Help
+		}
+	}
+
+	defined := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		defined[target.Name] = true
+	}
+	for _, name := range []string{"test", "clean"} {
+		if !defined[name] {
+			ctx.Remove = append(ctx.Remove, name)
+		}
+	}
+	if len(targets) == 0 {
+		ctx.Remove = append(ctx.Remove, "build")
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{makeContext: ctx}}
+}
+
+// makeTargets returns the targets of a makefile and of the makefiles it
+// includes by literal path, in the order they are defined
+func makeTargets(path string) []makeTarget {
+	r := &makefileReader{
+		files: map[string]bool{path: true},
+		phony: make(map[string]bool),
+	}
+	r.read(path, 0)
+
+	var targets []makeTarget
+	seen := make(map[string]bool)
+	for _, target := range r.targets {
+		if isMakeCommand(target, r.phony) && !seen[target.Name] {
+			seen[target.Name] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// makefileReader collects the rules of a makefile and the files it includes
+type makefileReader struct {
+	files   map[string]bool
+	phony   map[string]bool
+	targets []makeTarget
+}
+
+// read adds the rules of one makefile, following include directives up to
+// maxMakeIncludes deep
+func (r *makefileReader) read(path string, depth int) {
+	data, ok := readProjectFile(path)
+	if !ok {
+		return
+	}
+
+	help, inDefine := "", false
+	for _, line := range strings.Split(joinContinuations(string(data)), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		// Recipes and the bodies of define blocks are not rules
+		switch {
+		case inDefine:
+			inDefine = trimmed != "endef"
+			continue
+		case strings.HasPrefix(line, "\t"):
+			help = ""
+			continue
+		case trimmed == "define" || strings.HasPrefix(trimmed, "define "):
+			inDefine = true
+			continue
+		}
+
+		// A "## comment" line describes the rule that follows it
+		if strings.HasPrefix(trimmed, "##") {
+			help = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+
+		if files, ok := makeIncludes(trimmed); ok {
+			for _, file := range files {
+				include := filepath.Join(filepath.Dir(path), file)
+				if depth < maxMakeIncludes && !r.files[include] {
+					r.files[include] = true
+					r.read(include, depth+1)
+				}
+			}
+			help = ""
+			continue
+		}
+
+		rule, comment, _ := strings.Cut(line, "#")
+		if !makeRule.MatchString(rule) {
+			help = ""
+			continue
+		}
+		if strings.HasPrefix(comment, "#") {
+			help = strings.TrimSpace(strings.TrimLeft(comment, "#"))
+		}
+
+		names, prereqs, _ := strings.Cut(rule, ":")
+		if strings.TrimSpace(names) == ".PHONY" {
+			for _, name := range strings.Fields(strings.TrimPrefix(prereqs, ":")) {
+				r.phony[name] = true
+			}
+		} else {
+			for _, name := range strings.Fields(names) {
+				r.targets = append(r.targets, makeTarget{Name: name, Help: help})
+			}
+		}
+		help = ""
+	}
+}
+
+// isMakeCommand reports whether a target should be offered as a command:
+// not a special target (.PHONY), a pattern rule (%.o) or a variable
+// reference, and not a file target such as main.o unless it is declared
+// .PHONY or documented
+func isMakeCommand(target makeTarget, phony map[string]bool) bool {
+	name := target.Name
+	if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "%$()") || !isCommandName(name) {
+		return false
+	}
+	if strings.Contains(name, ".") {
+		return phony[name] || target.Help != ""
+	}
+	return true
+}
+
+// makeIncludes returns the files of an include directive, reporting false
+// for other lines. Paths with variables or globs are skipped.
+func makeIncludes(line string) ([]string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	switch fields[0] {
+	case "include", "-include", "sinclude":
+	default:
+		return nil, false
+	}
+
+	var files []string
+	for _, file := range fields[1:] {
+		if !strings.ContainsAny(file, "$*?[") {
+			files = append(files, filepath.FromSlash(file))
+		}
+	}
+	return files, true
+}
+
+// joinContinuations joins lines that end with a backslash to the next one
+func joinContinuations(data string) string {
+	return strings.NewReplacer("\\\r\n", " ", "\\\n", " ").Replace(data)
+}
+
+// findProjectFile looks for the first of names in dir and as many parents
+// as context detection searches
+func findProjectFile(dir string, names []string) (string, bool) {
+	var path string
+	_, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		for _, name := range names {
+			if path = filepath.Join(searchDir, name); fileExists(path) {
+				return true
+			}
+		}
+		return false
+	})
+	return path, found
+}
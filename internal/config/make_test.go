@@ -0,0 +1,165 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMakeLayer tests the commands generated from makefile targets
+func TestMakeLayer(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		want       map[string]string
+		wantDesc   map[string]string
+		wantRemove []string
+	}{
+		{
+			name: "targets and help comments",
+			files: map[string]string{
+				"Makefile": `# Project makefile
+VERSION := 1.0
+CFLAGS ?= -O2
+BIN ::= app
+export GOFLAGS := -mod=mod
+
+.PHONY: build test docker.push
+.DEFAULT_GOAL := build
+
+## Build the binary
+build: main.o
+	cc -o app main.o
+
+test: build ## Run the test suite
+	./app --self-test
+
+docker.push:
+	docker push app
+
+main.o: main.c
+	cc -c main.c
+
+%.o: %.c
+	cc -c $<
+
+$(BIN)-debug:
+	cc -g -o $@ main.c
+
+define HELP
+usage: not-a-target: here
+endef
+
+lint fmt: \
+		deps
+	@echo $@
+`,
+			},
+			want: map[string]string{
+				"build":       "make build",
+				"test":        "make test",
+				"docker.push": "make docker.push",
+				"lint":        "make lint",
+				"fmt":         "make fmt",
+			},
+			wantDesc: map[string]string{
+				"build": "Build the binary",
+				"test":  "Run the test suite",
+			},
+			wantRemove: []string{"clean"},
+		},
+		{
+			name: "plain test and clean keep their defaults",
+			files: map[string]string{
+				"makefile": "all:\n\tcc main.c\n\ntest:\n\t./test.sh\n\nclean:\n\trm -f a.out\n",
+			},
+			want: map[string]string{"all": "make all"},
+		},
+		{
+			name: "included makefiles",
+			files: map[string]string{
+				"Makefile":     "include mk/docker.mk\n-include local.mk $(EXTRA)\n\nall: ## Build everything\n",
+				"mk/docker.mk": "docker: ## Build the image\n\tdocker build .\n",
+				"local.mk":     "include Makefile\n\nclean:\n",
+			},
+			want: map[string]string{
+				"all":    "make all",
+				"docker": "make docker",
+			},
+			wantDesc: map[string]string{
+				"all":    "Build everything",
+				"docker": "Build the image",
+			},
+			wantRemove: []string{"test"},
+		},
+		{
+			name:       "no targets",
+			files:      map[string]string{"Makefile": "# nothing yet\nCC = gcc\n"},
+			want:       map[string]string{},
+			wantRemove: []string{"test", "clean", "build"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			layer := makeLayer(dir)
+			if layer == nil {
+				t.Fatal("makeLayer() = nil, want a layer")
+			}
+			ctx := layer.Contexts["make"]
+
+			got := make(map[string]string)
+			for name, cmd := range ctx.Commands {
+				got[name] = cmd.Run
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.wantDesc {
+				if desc := ctx.Description(name); desc != want {
+					t.Errorf("description of %q = %q, want %q", name, desc, want)
+				}
+			}
+			if !reflect.DeepEqual(ctx.Remove, tt.wantRemove) {
+				t.Errorf("Remove = %v, want %v", ctx.Remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+// TestLoadDir_Makefile tests that missing default targets are hidden and
+// present ones keep their descriptions
+func TestLoadDir_Makefile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Makefile": "all:\n\ntest:\n\tgo test ./...\n\nrelease: ## Tag a release\n"})
+
+	cfg, err := LoadDir("", dir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+	ctx := cfg.Contexts["make"]
+
+	tests := []struct {
+		command  string
+		wantRun  string
+		wantDesc string
+	}{
+		{"build", "make", "Build using Makefile (make)"},
+		{"test", "make test", "Run tests (make test)"},
+		{"release", "make release", "Tag a release"},
+		{"clean", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ctx.Commands[tt.command].Run; got != tt.wantRun {
+			t.Errorf("%s run = %q, want %q", tt.command, got, tt.wantRun)
+		}
+		if got := ctx.Description(tt.command); got != tt.wantDesc {
+			t.Errorf("%s description = %q, want %q", tt.command, got, tt.wantDesc)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// nodeContext is the name of the Node.js context
+const nodeContext = "node"
+
+// nodeLockfiles maps lockfiles to the package manager that writes them, in
+// the order they are checked when a directory has several
+var nodeLockfiles = []struct {
+	file    string
+	manager string
+}{
+	{"bun.lockb", "bun"},
+	{"bun.lock", "bun"},
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"package-lock.json", "npm"},
+}
+
+// packageJSON holds the fields of package.json that tb uses
+type packageJSON struct {
+	PackageManager string            `json:"packageManager"`
+	Scripts        map[string]string `json:"scripts"`
+}
+
+// nodeProject is the Node.js project a directory belongs to
+type nodeProject struct {
+	// Dir is the directory of the nearest package.json
+	Dir string
+
+	// PackageManager is npm, yarn, pnpm or bun
+	PackageManager string
+
+	// Scripts are the scripts of package.json
+	Scripts map[string]string
+}
+
+// nodeCommands returns the default commands of the node context for a
+// package manager
+func nodeCommands(pm string) ContextConfig {
+	// bun test runs Bun's own test runner rather than the test script
+	test, start := pm+" test", pm+" start"
+	if pm == "bun" {
+		test, start = "bun run test", "bun run start"
+	}
+
+	return ContextConfig{
+		Commands: map[string]Command{
+			"build":   {Run: pm + " run build"},
+			"test":    {Run: test},
+			"start":   {Run: start},
+			"dev":     {Run: pm + " run dev"},
+			"lint":    {Run: pm + " run lint"},
+			"install": {Run: pm + " install"},
+		},
+		Descriptions: map[string]string{
+			"build":   fmt.Sprintf("Build the project (%s run build)", pm),
+			"test":    fmt.Sprintf("Run tests (%s)", test),
+			"start":   fmt.Sprintf("Start the application (%s)", start),
+			"dev":     fmt.Sprintf("Start development server (%s run dev)", pm),
+			"lint":    fmt.Sprintf("Run linter (%s run lint)", pm),
+			"install": fmt.Sprintf("Install dependencies (%s install)", pm),
+		},
+	}
+}
+
+// nodeLayer returns the commands of the Node.js project dir belongs to: the
+// default commands for its package manager and one command per script of
+// package.json, described by the script itself. It is nil outside a Node.js
+// project. A script named install is left out so that tb install keeps
+// installing dependencies.
+func nodeLayer(dir string) *Config {
+	project, found := findNodeProject(dir)
+	if !found {
+		return nil
+	}
+
+	ctx := nodeCommands(project.PackageManager)
+	for name, script := range project.Scripts {
+		if name == "install" || !isCommandName(name) {
+			continue
+		}
+		ctx.Commands[name] = Command{Run: project.PackageManager + " run " + name}
+		// Completion shows one line per command
+		ctx.Descriptions[name] = strings.ReplaceAll(script, "\n", " ")
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{nodeContext: ctx}}
+}
+
+// findNodeProject looks for package.json in dir and as many parents as
+// context detection searches. The package manager is taken from the
+// packageManager field or a lockfile, in the project directory or, for a
+// package in a monorepo, a parent up to the repository root. It is npm if
+// neither is found.
+func findNodeProject(dir string) (*nodeProject, bool) {
+	var manifest packageJSON
+	projectDir, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		pkg, ok := readPackageJSON(filepath.Join(searchDir, "package.json"))
+		manifest = pkg
+		return ok
+	})
+	if !found {
+		return nil, false
+	}
+
+	project := &nodeProject{Dir: projectDir, Scripts: manifest.Scripts, PackageManager: "npm"}
+	contextpkg.WalkRepository(projectDir, func(searchDir string) bool {
+		if searchDir != projectDir {
+			manifest, _ = readPackageJSON(filepath.Join(searchDir, "package.json"))
+		}
+		if pm := packageManager(searchDir, manifest); pm != "" {
+			project.PackageManager = pm
+			return true
+		}
+		return false
+	})
+
+	return project, true
+}
+
+// packageManager returns the package manager that the packageManager field
+// of pkg, such as "pnpm@9.1.0", or a lockfile in dir names, or "" if there is
+// neither
+func packageManager(dir string, pkg packageJSON) string {
+	if name, _, _ := strings.Cut(pkg.PackageManager, "@"); name != "" {
+		for _, lock := range nodeLockfiles {
+			if lock.manager == name {
+				return name
+			}
+		}
+	}
+
+	for _, lock := range nodeLockfiles {
+		if fileExists(filepath.Join(dir, lock.file)) {
+			return lock.manager
+		}
+	}
+	return ""
+}
+
+// readPackageJSON reads package.json, reporting false if it is missing,
+// too large or not valid JSON
+func readPackageJSON(path string) (packageJSON, bool) {
+	var pkg packageJSON
+
+	data, ok := readProjectFile(path)
+	if !ok {
+		return pkg, false
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return packageJSON{}, false
+	}
+	return pkg, true
+}
+
+// readProjectFile reads a project file such as package.json or
+// pyproject.toml, reporting false if it is missing, not a regular file or
+// larger than MaxConfigFileSize
+func readProjectFile(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > MaxConfigFileSize {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// isCommandName reports whether a name taken from a project file, such as a
+// script of package.json, can be used as a command name: letters, digits and
+// "-_:." only, and not starting with a dash
+func isCommandName(name string) bool {
+	if name == "" || len(name) > 50 || strings.HasPrefix(name, "-") {
+		return false
+	}
+	for _, r := range name {
+		if !isAlphaNumeric(r) && !strings.ContainsRune("-_:.", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// pythonContext is the name of the Python context
+const pythonContext = "python"
+
+// pythonMarkers are the files that make a directory a Python project, as
+// context detection finds it
+var pythonMarkers = []string{"pyproject.toml", "setup.py", "Pipfile", "requirements.txt"}
+
+// pythonLockfiles maps lockfiles to the tool that writes them, in the order
+// they are checked
+var pythonLockfiles = []struct {
+	file string
+	tool string
+}{
+	{"uv.lock", "uv"},
+	{"poetry.lock", "poetry"},
+	{"pdm.lock", "pdm"},
+	{"Pipfile.lock", "pipenv"},
+}
+
+// pythonTools lists the [tool.*] tables of pyproject.toml that select a
+// tool when there is no lockfile. Hatch comes last because projects managed
+// by other tools often configure hatchling, its build backend, there.
+var pythonTools = []string{"uv", "poetry", "pdm", "hatch"}
+
+// noxSession matches a nox session function and the arguments of its
+// decorator
+var noxSession = regexp.MustCompile(`(?m)^@(?:nox\.)?session(?:\(([^)]*)\))?\s*\n(?:@.*\n)*def\s+(\w+)`)
+
+// noxSessionName matches the name argument of @nox.session
+var noxSessionName = regexp.MustCompile(`\bname\s*=\s*["']([^"']+)["']`)
+
+// pythonProject is the Python project a directory belongs to
+type pythonProject struct {
+	// Dir is the directory of the nearest project file
+	Dir string
+
+	// Tool manages the environment: uv, poetry, pdm, hatch, pipenv or pip
+	Tool string
+
+	// Scripts maps the console scripts of [project.scripts] or
+	// [tool.poetry.scripts] to their entry points
+	Scripts map[string]string
+
+	// ToxEnvs and NoxSessions are the environments of tox.ini or the
+	// [tool.tox] table and the sessions of noxfile.py
+	ToxEnvs     []string
+	NoxSessions []string
+
+	// Installable is set when the project can be installed with pip
+	// install -e ., Requirements when it has requirements.txt
+	Installable  bool
+	Requirements bool
+
+	// Main is set when the project has main.py
+	Main bool
+}
+
+// prefix returns what runs a program in the project's environment, such as
+// "uv run "
+func (p *pythonProject) prefix() string {
+	if p.Tool == "pip" {
+		return ""
+	}
+	return p.Tool + " run "
+}
+
+// pythonLayer returns the commands of the Python project dir belongs to:
+// install, test, lint, fmt and run for the tool that manages it, one command
+// per console script, and tox:<env> and nox:<session> commands. It is nil
+// outside a Python project.
+func pythonLayer(dir string) *Config {
+	project, found := findPythonProject(dir)
+	if !found {
+		return nil
+	}
+
+	ctx := pythonCommands(project)
+	for name, entry := range project.Scripts {
+		if name == "install" || !isCommandName(name) {
+			continue
+		}
+		ctx.Commands[name] = Command{Run: project.prefix() + name}
+		ctx.Descriptions[name] = "Run " + entry
+	}
+	for _, env := range project.ToxEnvs {
+		if name := "tox:" + env; isCommandName(name) {
+			ctx.Commands[name] = Command{Run: "tox -e " + env}
+			ctx.Descriptions[name] = fmt.Sprintf("Run the %s tox environment", env)
+		}
+	}
+	for _, session := range project.NoxSessions {
+		if name := "nox:" + session; isCommandName(name) {
+			ctx.Commands[name] = Command{Run: "nox -s " + session}
+			ctx.Descriptions[name] = fmt.Sprintf("Run the %s nox session", session)
+		}
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{pythonContext: ctx}}
+}
+
+// pythonCommands returns the install, test, lint, fmt and run commands of a
+// Python project. Run starts the only console script or main.py. Install and
+// run are removed when the project gives them nothing to do.
+func pythonCommands(p *pythonProject) ContextConfig {
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+	add := func(name, run, desc string) {
+		ctx.Commands[name] = Command{Run: run}
+		ctx.Descriptions[name] = fmt.Sprintf("%s (%s)", desc, run)
+	}
+
+	switch p.Tool {
+	case "uv":
+		add("install", "uv sync", "Install dependencies")
+	case "poetry":
+		add("install", "poetry install", "Install dependencies")
+	case "pdm":
+		add("install", "pdm install", "Install dependencies")
+	case "pipenv":
+		add("install", "pipenv install --dev", "Install dependencies")
+	case "hatch":
+		add("install", "hatch env create", "Create the default environment")
+	default:
+		if p.Requirements {
+			add("install", "pip install -r requirements.txt", "Install dependencies from requirements.txt")
+		} else if p.Installable {
+			add("install", "pip install -e .", "Install the project in editable mode")
+		} else {
+			ctx.Remove = append(ctx.Remove, "install")
+		}
+	}
+
+	if p.Tool == "hatch" {
+		add("test", "hatch test", "Run tests")
+		add("lint", "hatch fmt --check", "Check code")
+		add("fmt", "hatch fmt", "Format code")
+	} else {
+		add("test", p.prefix()+"pytest", "Run tests with pytest")
+		add("lint", p.prefix()+"ruff check .", "Check code with ruff")
+		add("fmt", p.prefix()+"black .", "Format code with black")
+	}
+
+	switch {
+	case len(p.Scripts) == 1:
+		for name := range p.Scripts {
+			add("run", p.prefix()+name, "Run "+name)
+		}
+	case p.Main:
+		add("run", p.prefix()+"python main.py", "Run main.py")
+	default:
+		ctx.Remove = append(ctx.Remove, "run")
+	}
+
+	return ctx
+}
+
+// findPythonProject looks for a Python project file in dir and as many
+// parents as context detection searches, and reads the project's tooling
+// from pyproject.toml, lockfiles, Pipfile, tox.ini and noxfile.py there
+func findPythonProject(dir string) (*pythonProject, bool) {
+	projectDir, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		for _, marker := range pythonMarkers {
+			if fileExists(filepath.Join(searchDir, marker)) {
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		return nil, false
+	}
+	return readPythonProject(projectDir), true
+}
+
+// readPythonProject reads the tooling of the Python project in dir. Files
+// that cannot be read or parsed are ignored.
+func readPythonProject(dir string) *pythonProject {
+	p := &pythonProject{
+		Dir:          dir,
+		Scripts:      make(map[string]string),
+		Requirements: fileExists(filepath.Join(dir, "requirements.txt")),
+		Main:         fileExists(filepath.Join(dir, "main.py")),
+		Installable:  fileExists(filepath.Join(dir, "setup.py")),
+	}
+
+	var pyproject map[string]interface{}
+	if data, ok := readProjectFile(filepath.Join(dir, "pyproject.toml")); ok {
+		pyproject, _ = contextpkg.ParseTOML(data)
+	}
+
+	for _, lock := range pythonLockfiles {
+		if fileExists(filepath.Join(dir, lock.file)) {
+			p.Tool = lock.tool
+			break
+		}
+	}
+	if p.Tool == "" {
+		for _, tool := range pythonTools {
+			if _, ok := tomlTable(pyproject, "tool", tool); ok {
+				p.Tool = tool
+				break
+			}
+		}
+	}
+	if p.Tool == "" && fileExists(filepath.Join(dir, "Pipfile")) {
+		p.Tool = "pipenv"
+	}
+	if p.Tool == "" {
+		p.Tool = "pip"
+	}
+
+	if project, ok := tomlTable(pyproject, "project"); ok {
+		p.Installable = true
+		addScripts(p.Scripts, project["scripts"])
+	}
+	if poetry, ok := tomlTable(pyproject, "tool", "poetry"); ok {
+		addScripts(p.Scripts, poetry["scripts"])
+	}
+
+	if data, ok := readProjectFile(filepath.Join(dir, "tox.ini")); ok {
+		p.ToxEnvs = toxEnvsFromINI(string(data))
+	} else if tox, ok := tomlTable(pyproject, "tool", "tox"); ok {
+		p.ToxEnvs = toxEnvsFromTOML(tox)
+	}
+	if data, ok := readProjectFile(filepath.Join(dir, "noxfile.py")); ok {
+		p.NoxSessions = noxSessions(string(data))
+	}
+
+	return p
+}
+
+// tomlTable returns the table at a key path of a parsed TOML document
+func tomlTable(doc map[string]interface{}, key ...string) (map[string]interface{}, bool) {
+	table := doc
+	for _, k := range key {
+		next, ok := table[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		table = next
+	}
+	return table, table != nil
+}
+
+// addScripts adds the console scripts of a scripts table. Poetry also allows
+// tables for scripts that are not console scripts; those are skipped.
+func addScripts(scripts map[string]string, table interface{}) {
+	entries, _ := table.(map[string]interface{})
+	for name, entry := range entries {
+		if ref, ok := entry.(string); ok {
+			scripts[name] = ref
+		}
+	}
+}
+
+// toxEnvsFromINI returns the environments of a tox.ini file: those of the
+// env_list (or envlist) of [tox], then those that only have a
+// [testenv:name] section
+func toxEnvsFromINI(data string) []string {
+	var envs []string
+	section, listing := "", false
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		// Indented lines continue the value of the previous key
+		if listing && line[0] != ' ' && line[0] != '\t' {
+			listing = false
+		}
+		if listing {
+			envs = append(envs, splitToxEnvs(trimmed)...)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if name, ok := strings.CutPrefix(section, "testenv:"); ok {
+				envs = append(envs, expandToxEnv(strings.TrimSpace(name))...)
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, "=")
+		key = strings.TrimSpace(key)
+		if section == "tox" && found && (key == "envlist" || key == "env_list") {
+			envs = append(envs, splitToxEnvs(value)...)
+			listing = true
+		}
+	}
+	return uniqueStrings(envs)
+}
+
+// toxEnvsFromTOML returns the environments of the [tool.tox] table of
+// pyproject.toml, either native (env_list and [tool.tox.env.name]) or an
+// embedded legacy_tox_ini
+func toxEnvsFromTOML(tox map[string]interface{}) []string {
+	if legacy, ok := tox["legacy_tox_ini"].(string); ok {
+		return toxEnvsFromINI(legacy)
+	}
+
+	var envs []string
+	list, _ := tox["env_list"].([]interface{})
+	for _, env := range list {
+		if name, ok := env.(string); ok {
+			envs = append(envs, expandToxEnv(name)...)
+		}
+	}
+	if tables, ok := tomlTable(tox, "env"); ok {
+		names := make([]string, 0, len(tables))
+		for name := range tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		envs = append(envs, names...)
+	}
+	return uniqueStrings(envs)
+}
+
+// splitToxEnvs splits an env_list value on commas outside braces and expands
+// each entry
+func splitToxEnvs(value string) []string {
+	var envs []string
+	depth, start := 0, 0
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) {
+			switch value[i] {
+			case '{':
+				depth++
+				continue
+			case '}':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if entry := strings.TrimSpace(value[start:i]); entry != "" {
+			envs = append(envs, expandToxEnv(entry)...)
+		}
+		start = i + 1
+	}
+	return envs
+}
+
+// expandToxEnv expands the brace groups of a tox environment name, so that
+// py3{11,12}-lint becomes py311-lint and py312-lint
+func expandToxEnv(name string) []string {
+	open := strings.Index(name, "{")
+	if open < 0 {
+		return []string{name}
+	}
+	end := strings.Index(name[open:], "}")
+	if end < 0 {
+		return []string{name}
+	}
+	end += open
+
+	var envs []string
+	for _, alt := range strings.Split(name[open+1:end], ",") {
+		envs = append(envs, expandToxEnv(name[:open]+strings.TrimSpace(alt)+name[end+1:])...)
+	}
+	return envs
+}
+
+// noxSessions returns the sessions defined in a noxfile, named by their
+// function or the name argument of the decorator
+func noxSessions(data string) []string {
+	var sessions []string
+	for _, m := range noxSession.FindAllStringSubmatch(data, -1) {
+		name := m[2]
+		if arg := noxSessionName.FindStringSubmatch(m[1]); arg != nil {
+			name = arg[1]
+		}
+		sessions = append(sessions, name)
+	}
+	return uniqueStrings(sessions)
+}
+
+// uniqueStrings drops repeated entries, keeping the first of each
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	unique := list[:0]
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files, given by slash-separated paths relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directories: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// TestPythonLayer tests the commands generated for each Python tool
+func TestPythonLayer(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		want       map[string]string
+		wantRemove []string
+	}{
+		{
+			name: "uv lockfile",
+			files: map[string]string{
+				"pyproject.toml": "[project]\nname = \"app\"\n[tool.hatch.build]\npackages = [\"src/app\"]\n",
+				"uv.lock":        "",
+			},
+			want: map[string]string{
+				"install": "uv sync",
+				"test":    "uv run pytest",
+				"lint":    "uv run ruff check .",
+				"fmt":     "uv run black .",
+			},
+			wantRemove: []string{"run"},
+		},
+		{
+			name:  "uv table",
+			files: map[string]string{"pyproject.toml": "[tool.uv]\ndev-dependencies = []\n", "main.py": ""},
+			want: map[string]string{
+				"install": "uv sync",
+				"test":    "uv run pytest",
+				"lint":    "uv run ruff check .",
+				"fmt":     "uv run black .",
+				"run":     "uv run python main.py",
+			},
+		},
+		{
+			name: "poetry with a script",
+			files: map[string]string{
+				"pyproject.toml": "[tool.poetry]\nname = \"app\"\n\n[tool.poetry.scripts]\napp = \"app.cli:main\"\n",
+			},
+			want: map[string]string{
+				"install": "poetry install",
+				"test":    "poetry run pytest",
+				"lint":    "poetry run ruff check .",
+				"fmt":     "poetry run black .",
+				"run":     "poetry run app",
+				"app":     "poetry run app",
+			},
+		},
+		{
+			name:  "pdm",
+			files: map[string]string{"pyproject.toml": "[project]\nname = \"app\"\n", "pdm.lock": ""},
+			want: map[string]string{
+				"install": "pdm install",
+				"test":    "pdm run pytest",
+				"lint":    "pdm run ruff check .",
+				"fmt":     "pdm run black .",
+			},
+			wantRemove: []string{"run"},
+		},
+		{
+			name: "hatch",
+			files: map[string]string{
+				"pyproject.toml": "[project]\nname = \"app\"\n\n[project.scripts]\napp = \"app:main\"\napp-admin = \"app.admin:main\"\n\n[tool.hatch.envs.default]\n",
+			},
+			want: map[string]string{
+				"install":   "hatch env create",
+				"test":      "hatch test",
+				"lint":      "hatch fmt --check",
+				"fmt":       "hatch fmt",
+				"app":       "hatch run app",
+				"app-admin": "hatch run app-admin",
+			},
+			wantRemove: []string{"run"},
+		},
+		{
+			name:  "pipenv",
+			files: map[string]string{"Pipfile": "[packages]\n", "main.py": ""},
+			want: map[string]string{
+				"install": "pipenv install --dev",
+				"test":    "pipenv run pytest",
+				"lint":    "pipenv run ruff check .",
+				"fmt":     "pipenv run black .",
+				"run":     "pipenv run python main.py",
+			},
+		},
+		{
+			name:  "pip with requirements",
+			files: map[string]string{"requirements.txt": "requests\n", "main.py": ""},
+			want: map[string]string{
+				"install": "pip install -r requirements.txt",
+				"test":    "pytest",
+				"lint":    "ruff check .",
+				"fmt":     "black .",
+				"run":     "python main.py",
+			},
+		},
+		{
+			name: "pip with tox and nox",
+			files: map[string]string{
+				"setup.py":   "",
+				"tox.ini":    "[tox]\nenvlist = py3{11,12}, lint\n",
+				"noxfile.py": "import nox\n\n@nox.session\ndef docs(session):\n    pass\n",
+			},
+			want: map[string]string{
+				"install":   "pip install -e .",
+				"test":      "pytest",
+				"lint":      "ruff check .",
+				"fmt":       "black .",
+				"tox:py311": "tox -e py311",
+				"tox:py312": "tox -e py312",
+				"tox:lint":  "tox -e lint",
+				"nox:docs":  "nox -s docs",
+			},
+			wantRemove: []string{"run"},
+		},
+		{
+			name:  "pyproject without a project table",
+			files: map[string]string{"pyproject.toml": "[tool.ruff]\nline-length = 100\n"},
+			want: map[string]string{
+				"test": "pytest",
+				"lint": "ruff check .",
+				"fmt":  "black .",
+			},
+			wantRemove: []string{"install", "run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			layer := pythonLayer(dir)
+			if layer == nil {
+				t.Fatal("pythonLayer() = nil, want a layer")
+			}
+			ctx := layer.Contexts["python"]
+
+			got := make(map[string]string)
+			for name, cmd := range ctx.Commands {
+				got[name] = cmd.Run
+				if ctx.Description(name) == "" {
+					t.Errorf("command %q has no description", name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(ctx.Remove, tt.wantRemove) {
+				t.Errorf("Remove = %v, want %v", ctx.Remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+// TestPythonLayer_NoProject tests that there is no layer outside a Python project
+func TestPythonLayer_NoProject(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"tox.ini": "[tox]\nenvlist = py312\n"})
+
+	if layer := pythonLayer(dir); layer != nil {
+		t.Errorf("pythonLayer() = %+v, want nil", layer)
+	}
+}
+
+// TestLoadDir_PythonProject tests that the Python layer replaces the
+// defaults and is overridden by config files
+func TestLoadDir_PythonProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pyproject.toml":  "[project]\nname = \"app\"\n",
+		"uv.lock":         "",
+		ProjectConfigName: "contexts:\n  python:\n    commands:\n      lint: uv run mypy .\n",
+	})
+
+	cfg, err := LoadDir("", dir)
+	if err != nil {
+		t.Fatalf("LoadDir() unexpected error: %v", err)
+	}
+	python := cfg.Contexts["python"]
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"install", "uv sync"},
+		{"test", "uv run pytest"},
+		{"lint", "uv run mypy ."},
+		{"run", ""},
+	}
+
+	for _, tt := range tests {
+		if got := python.Commands[tt.command].Run; got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+// TestToxEnvsFromINI tests reading the environments of tox.ini
+func TestToxEnvsFromINI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "single line",
+			input: "[tox]\nenvlist = py311, py312,lint\n",
+			want:  []string{"py311", "py312", "lint"},
+		},
+		{
+			name:  "continuation lines and testenv sections",
+			input: "[tox]\nenv_list =\n    py3{11,12}-django{42,50}\n    type\nskipsdist = true\n\n[testenv]\ndeps = pytest\n\n[testenv:type]\ncommands = mypy .\n\n[testenv:docs]\ncommands = sphinx-build docs out\n",
+			want:  []string{"py311-django42", "py311-django50", "py312-django42", "py312-django50", "type", "docs"},
+		},
+		{
+			name:  "comments",
+			input: "# envlist = old\n[tox]\n; note\nenvlist = py312\n",
+			want:  []string{"py312"},
+		},
+		{
+			name:  "envlist outside tox section",
+			input: "[flake8]\nenvlist = nope\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toxEnvsFromINI(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toxEnvsFromINI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestToxEnvsFromTOML tests reading the environments of [tool.tox]
+func TestToxEnvsFromTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "native",
+			input: "[tool.tox]\nenv_list = [\"py312\", \"lint\"]\n\n[tool.tox.env.lint]\ncommands = [[\"ruff\", \"check\"]]\n\n[tool.tox.env.docs]\ncommands = [[\"mkdocs\", \"build\"]]\n",
+			want:  []string{"py312", "lint", "docs"},
+		},
+		{
+			name:  "legacy ini",
+			input: "[tool.tox]\nlegacy_tox_ini = \"\"\"\n[tox]\nenvlist = py311, py312\n\"\"\"\n",
+			want:  []string{"py311", "py312"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"pyproject.toml": tt.input})
+
+			project, found := findPythonProject(dir)
+			if !found {
+				t.Fatal("findPythonProject() found no project")
+			}
+			if !reflect.DeepEqual(project.ToxEnvs, tt.want) {
+				t.Errorf("ToxEnvs = %v, want %v", project.ToxEnvs, tt.want)
+			}
+		})
+	}
+}
+
+// TestNoxSessions tests finding the sessions of a noxfile
+func TestNoxSessions(t *testing.T) {
+	noxfile := `import nox
+from nox import session
+
+@nox.session(python=["3.11", "3.12"])
+def tests(session: nox.Session) -> None:
+    session.run("pytest")
+
+
+@nox.session
+def lint(session):
+    session.run("ruff", "check")
+
+
+@session(name="type-check")
+@nox.parametrize("strict", [True, False])
+def mypy(session, strict):
+    session.run("mypy")
+
+
+def helper():
+    pass
+`
+
+	want := []string{"tests", "lint", "type-check"}
+	if got := noxSessions(noxfile); !reflect.DeepEqual(got, want) {
+		t.Errorf("noxSessions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+)
+
+// rustContext is the name of the Rust context
+const rustContext = "rust"
+
+// rustCrate is a package of a Cargo project
+type rustCrate struct {
+	// Name is the package name
+	Name string
+
+	// Bins are the names of its binary targets
+	Bins []string
+}
+
+// rustProject is the Cargo package or workspace a directory belongs to
+type rustProject struct {
+	// Root is the directory of the workspace, or of the package if it is
+	// not part of one
+	Root string
+
+	// Crates are the packages of the workspace, sorted by name
+	Crates []rustCrate
+
+	// DefaultRun is set when the root package names its default binary
+	DefaultRun bool
+
+	// Aliases are the [alias] entries of .cargo/config.toml, mapped to
+	// what they expand to
+	Aliases map[string]string
+}
+
+// rustLayer returns the commands of the Cargo project dir belongs to:
+// test:<crate> for every package, run:<bin> for every binary and one command
+// per cargo alias. Run starts the only binary, and is removed when there is
+// none or several without a default-run. It is nil outside a Cargo project.
+func rustLayer(dir string) *Config {
+	project, found := findRustProject(dir)
+	if !found {
+		return nil
+	}
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+
+	var bins []string
+	for _, crate := range project.Crates {
+		if name := "test:" + crate.Name; isCommandName(name) {
+			run := "cargo test -p " + crate.Name
+			ctx.Commands[name] = Command{Run: run}
+			ctx.Descriptions[name] = fmt.Sprintf("Test the %s crate (%s)", crate.Name, run)
+		}
+		for _, bin := range crate.Bins {
+			name := "run:" + bin
+			if _, exists := ctx.Commands[name]; exists || !isCommandName(name) {
+				continue
+			}
+			run := fmt.Sprintf("cargo run -p %s --bin %s", crate.Name, bin)
+			ctx.Commands[name] = Command{Run: run}
+			ctx.Descriptions[name] = fmt.Sprintf("Run %s (%s)", bin, run)
+			bins = append(bins, bin)
+		}
+	}
+
+	switch {
+	case len(bins) == 1:
+		run := ctx.Commands["run:"+bins[0]]
+		ctx.Commands["run"] = run
+		ctx.Descriptions["run"] = fmt.Sprintf("Run the binary (%s)", run.Run)
+	case len(bins) == 0 || !project.DefaultRun:
+		ctx.Remove = []string{"run"}
+	}
+
+	// Cargo does not let aliases replace its own commands, so neither does tb
+	defaults := getDefaultConfig().Contexts[rustContext].Commands
+	for alias, expansion := range project.Aliases {
+		if _, builtin := defaults[alias]; builtin || !isCommandName(alias) {
+			continue
+		}
+		if _, exists := ctx.Commands[alias]; exists {
+			continue
+		}
+		ctx.Commands[alias] = Command{Run: "cargo " + alias}
+		ctx.Descriptions[alias] = expansion
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{rustContext: ctx}}
+}
+
+// findRustProject looks for Cargo.toml in dir and as many parents as context
+// detection searches, then for the workspace the package belongs to further
+// up to the repository root, as cargo would
+func findRustProject(dir string) (*rustProject, bool) {
+	var manifest map[string]interface{}
+	searchDir, found := contextpkg.WalkProject(dir, func(searchDir string, _ int) bool {
+		doc, ok := readCargoManifest(searchDir)
+		manifest = doc
+		return ok
+	})
+	if !found {
+		return nil, false
+	}
+
+	project := &rustProject{Root: searchDir}
+	if _, ok := tomlTable(manifest, "workspace"); !ok {
+		if root, doc, found := findCargoWorkspace(searchDir); found {
+			project.Root, manifest = root, doc
+		}
+	}
+
+	// The root manifest is a package, a workspace or both
+	crates := make(map[string]rustCrate)
+	if crate, ok := readRustCrate(project.Root, manifest); ok {
+		crates[crate.Name] = crate
+		if pkg, _ := tomlTable(manifest, "package"); pkg["default-run"] != nil {
+			project.DefaultRun = true
+		}
+	}
+	for _, member := range cargoMembers(project.Root, manifest) {
+		if doc, ok := readCargoManifest(member); ok {
+			if crate, ok := readRustCrate(member, doc); ok {
+				crates[crate.Name] = crate
+			}
+		}
+	}
+
+	for _, crate := range crates {
+		project.Crates = append(project.Crates, crate)
+	}
+	sort.Slice(project.Crates, func(i, j int) bool {
+		return project.Crates[i].Name < project.Crates[j].Name
+	})
+
+	project.Aliases = cargoAliases(searchDir)
+	return project, true
+}
+
+// findCargoWorkspace looks for a Cargo.toml with a [workspace] table in the
+// parents of dir, up to the repository root
+func findCargoWorkspace(dir string) (string, map[string]interface{}, bool) {
+	var manifest map[string]interface{}
+	root, found := contextpkg.WalkRepository(dir, func(searchDir string) bool {
+		if searchDir == dir {
+			return false
+		}
+		doc, ok := readCargoManifest(searchDir)
+		if _, isWorkspace := tomlTable(doc, "workspace"); ok && isWorkspace {
+			manifest = doc
+			return true
+		}
+		return false
+	})
+	return root, manifest, found
+}
+
+// readCargoManifest reads and parses the Cargo.toml of dir
+func readCargoManifest(dir string) (map[string]interface{}, bool) {
+	data, ok := readProjectFile(filepath.Join(dir, "Cargo.toml"))
+	if !ok {
+		return nil, false
+	}
+	doc, err := contextpkg.ParseTOML(data)
+	if err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// cargoMembers returns the directories of the workspace members of a
+// manifest, expanding globs and leaving out excluded directories
+func cargoMembers(root string, manifest map[string]interface{}) []string {
+	workspace, ok := tomlTable(manifest, "workspace")
+	if !ok {
+		return nil
+	}
+
+	excluded := make(map[string]bool)
+	for _, pattern := range tomlStrings(workspace["exclude"]) {
+		excluded[filepath.Join(root, filepath.FromSlash(pattern))] = true
+	}
+
+	var members []string
+	for _, pattern := range tomlStrings(workspace["members"]) {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		if err != nil {
+			continue
+		}
+		for _, member := range matches {
+			if !excluded[member] && member != root {
+				members = append(members, member)
+			}
+		}
+	}
+	return members
+}
+
+// readRustCrate reads the package of a manifest and its binary targets:
+// [[bin]] entries, src/main.rs and the files and directories of src/bin
+func readRustCrate(dir string, manifest map[string]interface{}) (rustCrate, bool) {
+	pkg, ok := tomlTable(manifest, "package")
+	if !ok {
+		return rustCrate{}, false
+	}
+	name, _ := pkg["name"].(string)
+	if name == "" {
+		return rustCrate{}, false
+	}
+
+	crate := rustCrate{Name: name}
+	seen := make(map[string]bool)
+	addBin := func(bin string) {
+		if bin != "" && !seen[bin] {
+			seen[bin] = true
+			crate.Bins = append(crate.Bins, bin)
+		}
+	}
+
+	targets, _ := manifest["bin"].([]interface{})
+	for _, target := range targets {
+		if table, ok := target.(map[string]interface{}); ok {
+			bin, _ := table["name"].(string)
+			addBin(bin)
+		}
+	}
+
+	// Targets are discovered automatically unless autobins = false
+	if auto, ok := pkg["autobins"].(bool); !ok || auto {
+		if fileExists(filepath.Join(dir, "src", "main.rs")) {
+			addBin(name)
+		}
+		entries, _ := os.ReadDir(filepath.Join(dir, "src", "bin"))
+		for _, entry := range entries {
+			switch {
+			case entry.IsDir() && fileExists(filepath.Join(dir, "src", "bin", entry.Name(), "main.rs")):
+				addBin(entry.Name())
+			case !entry.IsDir() && strings.HasSuffix(entry.Name(), ".rs"):
+				addBin(strings.TrimSuffix(entry.Name(), ".rs"))
+			}
+		}
+	}
+
+	sort.Strings(crate.Bins)
+	return crate, true
+}
+
+// cargoAliases reads the [alias] tables of .cargo/config.toml (or the older
+// .cargo/config) in dir and its parents up to the repository root. Closer
+// files take precedence, as in cargo. Array aliases are joined with spaces.
+func cargoAliases(dir string) map[string]string {
+	aliases := make(map[string]string)
+	contextpkg.WalkRepository(dir, func(dir string) bool {
+		for _, name := range []string{"config.toml", "config"} {
+			data, ok := readProjectFile(filepath.Join(dir, ".cargo", name))
+			if !ok {
+				continue
+			}
+			doc, err := contextpkg.ParseTOML(data)
+			if err != nil {
+				break
+			}
+			table, _ := tomlTable(doc, "alias")
+			for alias, value := range table {
+				if _, exists := aliases[alias]; exists {
+					continue
+				}
+				switch v := value.(type) {
+				case string:
+					aliases[alias] = v
+				case []interface{}:
+					aliases[alias] = strings.Join(tomlStrings(v), " ")
+				}
+			}
+			break
+		}
+		return false
+	})
+	return aliases
+}
+
+// tomlStrings returns the strings of a parsed TOML array
+func tomlStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var strs []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestRustLayer tests the per-crate, per-binary and alias commands of Cargo
+// packages and workspaces
+func TestRustLayer(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// dir is where the search starts, relative to the root
+		dir        string
+		want       map[string]string
+		wantDesc   map[string]string
+		wantRemove []string
+	}{
+		{
+			name: "package with one binary",
+			files: map[string]string{
+				"Cargo.toml":  "[package]\nname = \"tool\"\nversion = \"0.1.0\"\n",
+				"src/main.rs": "fn main() {}\n",
+			},
+			want: map[string]string{
+				"test:tool": "cargo test -p tool",
+				"run:tool":  "cargo run -p tool --bin tool",
+				"run":       "cargo run -p tool --bin tool",
+			},
+		},
+		{
+			name: "library",
+			files: map[string]string{
+				"Cargo.toml": "[package]\nname = \"lib\"\n",
+				"src/lib.rs": "",
+			},
+			want:       map[string]string{"test:lib": "cargo test -p lib"},
+			wantRemove: []string{"run"},
+		},
+		{
+			name: "several binaries with default-run",
+			files: map[string]string{
+				"Cargo.toml":             "[package]\nname = \"app\"\ndefault-run = \"app\"\n\n[[bin]]\nname = \"admin\"\npath = \"tools/admin.rs\"\n",
+				"src/main.rs":            "",
+				"src/bin/seed.rs":        "",
+				"src/bin/import/main.rs": "",
+				"src/bin/notes.txt":      "",
+			},
+			want: map[string]string{
+				"test:app":   "cargo test -p app",
+				"run:admin":  "cargo run -p app --bin admin",
+				"run:app":    "cargo run -p app --bin app",
+				"run:import": "cargo run -p app --bin import",
+				"run:seed":   "cargo run -p app --bin seed",
+			},
+		},
+		{
+			name: "workspace from a member",
+			files: map[string]string{
+				"Cargo.toml":                "[workspace]\nmembers = [\"crates/*\", \"cli\"]\nexclude = [\"crates/old\"]\n",
+				"cli/Cargo.toml":            "[package]\nname = \"cli\"\n",
+				"cli/src/main.rs":           "",
+				"crates/core/Cargo.toml":    "[package]\nname = \"core\"\n",
+				"crates/server/Cargo.toml":  "[package]\nname = \"server\"\n",
+				"crates/server/src/main.rs": "",
+				"crates/old/Cargo.toml":     "[package]\nname = \"old\"\n",
+				".cargo/config.toml":        "[alias]\nxtask = \"run --package xtask --\"\nci = [\"test\", \"--workspace\"]\nb = \"build\"\nbuild = \"build --release\"\n",
+				"crates/core/.cargo/config": "[alias]\nb = \"build --release\"\n",
+			},
+			dir: "crates/core/src",
+			want: map[string]string{
+				"test:cli":    "cargo test -p cli",
+				"test:core":   "cargo test -p core",
+				"test:server": "cargo test -p server",
+				"run:cli":     "cargo run -p cli --bin cli",
+				"run:server":  "cargo run -p server --bin server",
+				"xtask":       "cargo xtask",
+				"ci":          "cargo ci",
+				"b":           "cargo b",
+			},
+			wantDesc: map[string]string{
+				"xtask": "run --package xtask --",
+				"ci":    "test --workspace",
+				"b":     "build --release",
+			},
+			wantRemove: []string{"run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{".git/HEAD": ""})
+			writeFiles(t, root, tt.files)
+
+			layer := rustLayer(filepath.Join(root, filepath.FromSlash(tt.dir)))
+			if layer == nil {
+				t.Fatal("rustLayer() = nil, want a layer")
+			}
+			ctx := layer.Contexts["rust"]
+
+			got := make(map[string]string)
+			for name, cmd := range ctx.Commands {
+				got[name] = cmd.Run
+				if ctx.Description(name) == "" {
+					t.Errorf("command %q has no description", name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.wantDesc {
+				if desc := ctx.Description(name); desc != want {
+					t.Errorf("description of %q = %q, want %q", name, desc, want)
+				}
+			}
+			if !reflect.DeepEqual(ctx.Remove, tt.wantRemove) {
+				t.Errorf("Remove = %v, want %v", ctx.Remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+// TestRustLayer_NoProject tests that there is no layer outside a Cargo project
+func TestRustLayer_NoProject(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".git/HEAD": "", ".cargo/config.toml": "[alias]\nb = \"build\"\n"})
+
+	if layer := rustLayer(dir); layer != nil {
+		t.Errorf("rustLayer() = %+v, want nil", layer)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// taskContext is the name of the Task context
+const taskContext = "task"
+
+// taskfileNames are the file names task reads, in the order it tries them
+var taskfileNames = []string{
+	"Taskfile.yml", "taskfile.yml", "Taskfile.yaml", "taskfile.yaml",
+	"Taskfile.dist.yml", "taskfile.dist.yml", "Taskfile.dist.yaml", "taskfile.dist.yaml",
+}
+
+// taskfile holds the tasks of a Taskfile. A task is a mapping, or a command
+// or list of commands in the short form.
+type taskfile struct {
+	Tasks map[string]yaml.Node `yaml:"tasks"`
+}
+
+// taskDefinition holds the fields of a task that tb uses
+type taskDefinition struct {
+	Desc     string `yaml:"desc"`
+	Internal bool   `yaml:"internal"`
+}
+
+// taskLayer returns a command for every task of the Taskfile in dir or the
+// nearest parent, described by its desc. Internal tasks are left out. It is
+// nil when there is no Taskfile or it cannot be parsed.
+func taskLayer(dir string) *Config {
+	path, found := findProjectFile(dir, taskfileNames)
+	if !found {
+		return nil
+	}
+	data, ok := readProjectFile(path)
+	if !ok {
+		return nil
+	}
+	var file taskfile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+
+	ctx := ContextConfig{
+		Commands:     make(map[string]Command),
+		Descriptions: make(map[string]string),
+	}
+	for name, node := range file.Tasks {
+		var task taskDefinition
+		if node.Kind == yaml.MappingNode {
+			if err := node.Decode(&task); err != nil {
+				continue
+			}
+		}
+		if task.Internal || !isCommandName(name) {
+			continue
+		}
+		ctx.Commands[name] = Command{Run: "task " + name}
+		if task.Desc != "" {
+			ctx.Descriptions[name] = task.Desc
+		}
+	}
+
+	return &Config{Contexts: map[string]ContextConfig{taskContext: ctx}}
+}
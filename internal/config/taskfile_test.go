@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTaskLayer tests the commands generated from Taskfile tasks
+func TestTaskLayer(t *testing.T) {
+	taskfile := `version: '3'
+
+tasks:
+  build:
+    desc: Build the binary
+    cmds:
+      - go build ./...
+  lint: golangci-lint run
+  docs:serve:
+    desc: Serve the docs
+    cmds: [mkdocs serve]
+  setup:
+    internal: true
+    cmds: [go mod download]
+  "deploy:*":
+    cmds: [echo deploy]
+`
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Taskfile.yml": taskfile})
+
+	layer := taskLayer(dir)
+	if layer == nil {
+		t.Fatal("taskLayer() = nil, want a layer")
+	}
+	ctx := layer.Contexts["task"]
+
+	want := map[string]string{
+		"build":      "Build the binary",
+		"lint":       "",
+		"docs:serve": "Serve the docs",
+	}
+	got := make(map[string]string)
+	for name, cmd := range ctx.Commands {
+		if cmd.Run != "task "+name {
+			t.Errorf("command %q run = %q, want %q", name, cmd.Run, "task "+name)
+		}
+		got[name] = ctx.Description(name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks = %v, want %v", got, want)
+	}
+}
+
+// TestTaskLayer_Invalid tests that an unparsable Taskfile adds no commands
+func TestTaskLayer_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Taskfile.yml": "tasks: [\n"})
+
+	if layer := taskLayer(dir); layer != nil {
+		t.Errorf("taskLayer() = %+v, want nil", layer)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/bamf0/toolbox/internal/shellwords"
+)
+
+// templateFuncs declares the functions command templates can call. The
+// functions that run are bound when the command is expanded.
+var templateFuncs = template.FuncMap{
+	"arg":  func(ref interface{}) (string, error) { return "", nil },
+	"args": func() (string, error) { return "", nil },
+}
+
+// ParseTemplate parses one word, script or setting of a command as a
+// template. The arg and args functions must be replaced with Funcs before
+// it is executed.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("command").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// validateTemplates parses every template of a command, so that a syntax
+// error is reported when the configuration is loaded rather than when the
+// command runs
+func validateTemplates(cmd Command) error {
+	texts := append([]string{cmd.Dir}, cmd.Args...)
+	if cmd.Shell != "" {
+		texts = append(texts, cmd.Run)
+	} else {
+		words, err := shellwords.SplitTemplate(cmd.Run)
+		if err != nil {
+			return fmt.Errorf("invalid command line: %w", err)
+		}
+		texts = append(texts, words...)
+	}
+	for _, value := range cmd.Env {
+		texts = append(texts, value)
+	}
+
+	for _, text := range texts {
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+		if _, err := ParseTemplate(text); err != nil {
+			return fmt.Errorf("invalid template %q: %w", text, err)
+		}
+	}
+	return nil
+}
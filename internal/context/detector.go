@@ -1,92 +1,308 @@
 package context
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
-// Detector identifies the project context based on marker files
+const (
+	// MaxParentLevels is how many parent directories are searched
+	// besides the starting directory
+	MaxParentLevels = 3
+
+	// primaryWeight scores a context found by a primary marker or a plugin
+	primaryWeight = 1.0
+
+	// secondaryWeight scores a context found only by secondary markers
+	secondaryWeight = 0.5
+
+	// extraMarkerWeight is added for every further marker that matched
+	extraMarkerWeight = 0.1
+)
+
+// ErrNoContext is returned when no context is detected
+var ErrNoContext = errors.New("no recognized project context found")
+
+// priorityOrder breaks ties between built-in contexts with the same score
+var priorityOrder = []string{"node", "go", "python", "rust", "java", "ruby", "php", "make", "just", "task"}
+
+// Source detects contexts by other means than marker files. Plugins are
+// sources.
+type Source interface {
+	// Name identifies the source
+	Name() string
+
+	// Detect returns the context found in dir, if any
+	Detect(dir string) (string, bool)
+}
+
+// Candidate is a context found by detection
+type Candidate struct {
+	// Context is the context name
+	Context string `json:"context"`
+
+	// Score ranks candidates: 1 for a primary marker in the starting
+	// directory, less for secondary markers and for parent directories
+	Score float64 `json:"score"`
+
+	// Markers are the files that matched the context's rules, in Dir
+	Markers []string `json:"markers,omitempty"`
+
+	// Dir is the directory the context was found in
+	Dir string `json:"dir"`
+
+	// Distance is how many levels above the starting directory Dir is
+	Distance int `json:"distance"`
+
+	// Plugin is the plugin that found the context, or "" for markers
+	Plugin string `json:"plugin,omitempty"`
+
+	// Priority decides between candidates with the same score, higher first
+	Priority int `json:"priority"`
+}
+
+// Detector identifies the project context based on detection rules
 type Detector struct {
-	// Map of context name to marker files that identify it
-	markers map[string][]string
+	// Map of context name to the rules that identify it
+	rules map[string][]compiledRule
+
+	// sources detect contexts besides the rules
+	sources []Source
+
+	// priorities of contexts, 0 unless set
+	priorities map[string]int
 }
 
 // NewDetector creates a new context detector with default markers
 func NewDetector() *Detector {
-	return &Detector{
-		markers: map[string][]string{
-			"node":   {"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
-			"go":     {"go.mod", "go.sum"},
-			"python": {"pyproject.toml", "setup.py", "requirements.txt", "Pipfile"},
-			"rust":   {"Cargo.toml", "Cargo.lock"},
-			"make":   {"Makefile", "makefile"},
-			"ruby":   {"Gemfile", "Gemfile.lock"},
-			"java":   {"pom.xml", "build.gradle", "build.gradle.kts"},
-			"php":    {"composer.json", "composer.lock"},
-		},
+	d := &Detector{
+		rules:      make(map[string][]compiledRule),
+		priorities: make(map[string]int),
+	}
+	defaults := map[string][]Rule{
+		"node":   {{File: "package.json"}, {File: "package-lock.json", Secondary: true}, {File: "yarn.lock", Secondary: true}, {File: "pnpm-lock.yaml", Secondary: true}, {File: "bun.lockb", Secondary: true}, {File: "bun.lock", Secondary: true}},
+		"go":     {{File: "go.mod"}, {File: "go.work"}, {File: "go.sum", Secondary: true}},
+		"python": {{File: "pyproject.toml"}, {File: "setup.py"}, {File: "Pipfile"}, {File: "requirements.txt", Secondary: true}},
+		"rust":   {{File: "Cargo.toml"}, {File: "Cargo.lock", Secondary: true}},
+		"make":   {{File: "Makefile"}, {File: "makefile"}, {File: "GNUmakefile"}},
+		"just":   {{File: "justfile"}, {File: "Justfile"}, {File: ".justfile"}},
+		"task":   {{File: "Taskfile.yml"}, {File: "Taskfile.yaml"}, {File: "taskfile.yml"}, {File: "taskfile.yaml"}, {File: "Taskfile.dist.yml"}, {File: "Taskfile.dist.yaml"}},
+		"ruby":   {{File: "Gemfile"}, {File: "Gemfile.lock", Secondary: true}},
+		"java":   {{File: "pom.xml"}, {File: "build.gradle"}, {File: "build.gradle.kts"}},
+		"php":    {{File: "composer.json"}, {File: "composer.lock", Secondary: true}},
 	}
+	for ctx, rules := range defaults {
+		for _, rule := range rules {
+			d.rules[ctx] = append(d.rules[ctx], compiledRule{Rule: rule})
+		}
+	}
+	return d
 }
 
 // Detect identifies the project context by searching for marker files
-// Returns the first matching context or an error if none found
+// Returns the best ranked context or an error if none found
 func (d *Detector) Detect(dir string) (string, error) {
+	best, err := d.DetectBest(dir)
+	if err != nil {
+		return "", err
+	}
+	return best.Context, nil
+}
+
+// DetectBest returns the best ranked candidate or an error if none found
+func (d *Detector) DetectBest(dir string) (Candidate, error) {
+	candidates, err := d.DetectAll(dir)
+	if err != nil {
+		return Candidate{}, err
+	}
+	if len(candidates) == 0 {
+		absDir, _ := filepath.Abs(dir)
+		return Candidate{}, fmt.Errorf("%w in %s or parent directories", ErrNoContext, absDir)
+	}
+	return candidates[0], nil
+}
+
+// DetectAll returns every context found in dir and up to three levels of
+// parents, best first. Each context is listed once, for the directory where
+// it scored highest. Closer directories and primary markers score higher;
+// ties go to the higher priority, then to plugins, then to the built-in
+// priority order.
+func (d *Detector) DetectAll(dir string) ([]Candidate, error) {
+	return d.detectAll(dir, nil)
+}
+
+// detectAll implements DetectAll, recording every check in trace if it is
+// not nil
+func (d *Detector) detectAll(dir string, trace *Trace) ([]Candidate, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	best := make(map[string]Candidate)
+	keep := func(c Candidate) {
+		if current, exists := best[c.Context]; !exists || c.Score > current.Score {
+			best[c.Context] = c
+		}
 	}
 
 	// Search current directory and up to 3 levels of parents
 	// This allows detection even when in subdirectories
-	searchDir := absDir
-	for i := 0; i < 4; i++ {
-		context, found := d.detectInDirectory(searchDir)
-		if found {
-			return context, nil
+	WalkProject(absDir, func(searchDir string, distance int) bool {
+		var checks *DirTrace
+		if trace != nil {
+			trace.Dirs = append(trace.Dirs, DirTrace{Dir: searchDir, Distance: distance})
+			checks = &trace.Dirs[len(trace.Dirs)-1]
 		}
-
-		// Move up one directory
-		parent := filepath.Dir(searchDir)
-		if parent == searchDir {
-			// Reached root
-			break
+		for _, c := range d.detectInDirectory(searchDir, distance, checks) {
+			keep(c)
 		}
-		searchDir = parent
+		return false
+	})
+
+	candidates := make([]Candidate, 0, len(best))
+	for _, c := range best {
+		c.Priority = d.priorities[c.Context]
+		candidates = append(candidates, c)
 	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return rankBefore(candidates[i], candidates[j])
+	})
 
-	return "", fmt.Errorf("no recognized project context found in %s or parent directories", absDir)
+	return candidates, nil
 }
 
-// detectInDirectory checks for marker files in a specific directory
-func (d *Detector) detectInDirectory(dir string) (string, bool) {
-	// Check each context's markers
-	// Priority order matters - checked in map iteration order
-	// For deterministic results, we check in a specific order
-	priorityOrder := []string{"node", "go", "python", "rust", "java", "ruby", "php", "make"}
+// detectInDirectory returns the candidates found in a specific directory.
+// Sources are asked in the order they were added, then the rules of each
+// context are checked in name order; checks records each result if it is
+// not nil.
+func (d *Detector) detectInDirectory(dir string, distance int, checks *DirTrace) []Candidate {
+	var candidates []Candidate
+	factor := 1 / float64(1+distance)
 
-	for _, ctx := range priorityOrder {
-		markers, exists := d.markers[ctx]
-		if !exists {
-			continue
+	for _, source := range d.sources {
+		ctx, found := source.Detect(dir)
+		if checks != nil {
+			check := SourceCheck{Plugin: source.Name(), Found: found}
+			if found {
+				check.Context = ctx
+			}
+			checks.Sources = append(checks.Sources, check)
+		}
+		if found {
+			candidates = append(candidates, Candidate{
+				Context:  ctx,
+				Score:    primaryWeight * factor,
+				Dir:      dir,
+				Distance: distance,
+				Plugin:   source.Name(),
+			})
 		}
+	}
 
-		for _, marker := range markers {
-			markerPath := filepath.Join(dir, marker)
-			if fileExists(markerPath) {
-				return ctx, true
+	contexts := make([]string, 0, len(d.rules))
+	for ctx := range d.rules {
+		contexts = append(contexts, ctx)
+	}
+	sort.Strings(contexts)
+
+	for _, ctx := range contexts {
+		var matched []string
+		primary := false
+		for _, rule := range d.rules[ctx] {
+			file, found := rule.find(dir)
+			if checks != nil {
+				checks.Rules = append(checks.Rules, RuleCheck{Context: ctx, Rule: rule.Rule, File: file, Found: found})
+			}
+			if found {
+				matched = append(matched, file)
+				primary = primary || !rule.Secondary
 			}
 		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		weight := secondaryWeight
+		if primary {
+			weight = primaryWeight
+		}
+		weight += extraMarkerWeight * float64(len(matched)-1)
+		if weight > primaryWeight {
+			weight = primaryWeight
+		}
+
+		candidates = append(candidates, Candidate{
+			Context:  ctx,
+			Score:    weight * factor,
+			Markers:  matched,
+			Dir:      dir,
+			Distance: distance,
+		})
 	}
 
-	return "", false
+	return candidates
 }
 
-// AddMarker adds a custom marker file for a context
+// rankBefore orders candidates by score, then distance, then priority, then
+// plugins before marker files, then the built-in priority order, then name
+func rankBefore(a, b Candidate) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if (a.Plugin != "") != (b.Plugin != "") {
+		return a.Plugin != ""
+	}
+	if pa, pb := priority(a.Context), priority(b.Context); pa != pb {
+		return pa < pb
+	}
+	return a.Context < b.Context
+}
+
+// priority returns a context's position in priorityOrder; contexts added
+// with AddMarker come after the built-in ones
+func priority(ctx string) int {
+	for i, name := range priorityOrder {
+		if name == ctx {
+			return i
+		}
+	}
+	return len(priorityOrder)
+}
+
+// AddMarker adds a custom primary marker file for a context
 func (d *Detector) AddMarker(context, markerFile string) {
-	if _, exists := d.markers[context]; !exists {
-		d.markers[context] = []string{}
+	d.rules[context] = append(d.rules[context], compiledRule{Rule: Rule{File: markerFile}})
+}
+
+// AddRule adds a detection rule for a context
+func (d *Detector) AddRule(context string, rule Rule) error {
+	compiled, err := compileRule(rule)
+	if err != nil {
+		return err
 	}
-	d.markers[context] = append(d.markers[context], markerFile)
+	d.rules[context] = append(d.rules[context], compiled)
+	return nil
+}
+
+// SetPriority sets the priority that decides between a context and others
+// with the same score. Contexts have priority 0 by default.
+func (d *Detector) SetPriority(context string, priority int) {
+	d.priorities[context] = priority
+}
+
+// AddSource adds a source, such as a plugin, that detects contexts
+func (d *Detector) AddSource(source Source) {
+	d.sources = append(d.sources, source)
 }
 
 // FileExists checks if a file exists in the current directory
@@ -3,6 +3,7 @@ package context
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -193,9 +194,75 @@ func TestDetector_AddMarker(t *testing.T) {
 	detector := NewDetector()
 	detector.AddMarker("customctx", customMarker)
 
-	// This won't be detected without updating detectInDirectory priority order
-	// but tests that AddMarker doesn't panic
-	_, _ = detector.Detect(tmpDir)
+	ctx, err := detector.Detect(tmpDir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if ctx != "customctx" {
+		t.Errorf("expected context 'customctx', got %q", ctx)
+	}
+}
+
+// TestDetector_AddRule tests detecting contexts with rules
+func TestDetector_AddRule(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "App.csproj"), []byte("<Project />"), 0644); err != nil {
+		t.Fatalf("failed to create App.csproj: %v", err)
+	}
+
+	detector := NewDetector()
+	if err := detector.AddRule("dotnet", Rule{File: "*.csproj"}); err != nil {
+		t.Fatalf("AddRule() unexpected error: %v", err)
+	}
+	if err := detector.AddRule("broken", Rule{File: "*.csproj", Match: "("}); err == nil {
+		t.Error("AddRule() with an invalid rule: expected error, got nil")
+	}
+
+	candidates, err := detector.DetectAll(tmpDir)
+	if err != nil {
+		t.Fatalf("DetectAll() unexpected error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Context != "dotnet" || candidates[0].Markers[0] != "App.csproj" {
+		t.Errorf("DetectAll() = %+v, want dotnet from App.csproj", candidates)
+	}
+}
+
+// TestDetector_SetPriority tests that priority decides between equal scores only
+func TestDetector_SetPriority(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, file := range []string{"sub/package.json", "sub/next.config.js", "go.mod"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("{}"), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", file, err)
+		}
+	}
+
+	detector := NewDetector()
+	if err := detector.AddRule("nextjs", Rule{File: "next.config.js"}); err != nil {
+		t.Fatalf("AddRule() unexpected error: %v", err)
+	}
+	detector.SetPriority("nextjs", 10)
+	detector.SetPriority("go", 50)
+
+	candidates, err := detector.DetectAll(sub)
+	if err != nil {
+		t.Fatalf("DetectAll() unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.Context)
+	}
+	// go has the highest priority but was found further up
+	if want := []string{"nextjs", "node", "go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DetectAll() order = %v, want %v", got, want)
+	}
+	if candidates[0].Priority != 10 {
+		t.Errorf("nextjs priority = %d, want 10", candidates[0].Priority)
+	}
 }
 
 // TestFileExists tests the fileExists helper function
@@ -298,6 +365,141 @@ func TestDetector_Detect_TooDeep(t *testing.T) {
 	}
 }
 
+// fakeSource is a Source that detects a context in one directory
+type fakeSource struct {
+	dir string
+	ctx string
+}
+
+func (s fakeSource) Name() string { return "fake" }
+
+func (s fakeSource) Detect(dir string) (string, bool) {
+	return s.ctx, dir == s.dir
+}
+
+// TestDetector_DetectAll tests ranking, scores, markers and directories of candidates
+func TestDetector_DetectAll(t *testing.T) {
+	tests := []struct {
+		name string
+		// files maps paths relative to the root to create; the search starts in sub
+		files  []string
+		source string
+		want   []Candidate
+	}{
+		{
+			name:  "primary marker outranks secondary",
+			files: []string{"sub/go.sum", "sub/Gemfile"},
+			want: []Candidate{
+				{Context: "ruby", Score: 1, Markers: []string{"Gemfile"}},
+				{Context: "go", Score: 0.5, Markers: []string{"go.sum"}},
+			},
+		},
+		{
+			name:  "extra markers add to a secondary score",
+			files: []string{"sub/package-lock.json", "sub/yarn.lock", "sub/go.sum"},
+			want: []Candidate{
+				{Context: "node", Score: 0.6, Markers: []string{"package-lock.json", "yarn.lock"}},
+				{Context: "go", Score: 0.5, Markers: []string{"go.sum"}},
+			},
+		},
+		{
+			name:  "bun lockfile",
+			files: []string{"sub/bun.lockb"},
+			want: []Candidate{
+				{Context: "node", Score: 0.5, Markers: []string{"bun.lockb"}},
+			},
+		},
+		{
+			name:  "justfile and Taskfile",
+			files: []string{"sub/justfile", "sub/Taskfile.yml"},
+			want: []Candidate{
+				{Context: "just", Score: 1, Markers: []string{"justfile"}},
+				{Context: "task", Score: 1, Markers: []string{"Taskfile.yml"}},
+			},
+		},
+		{
+			name:  "closer directory wins",
+			files: []string{"package.json", "sub/go.mod", "sub/go.sum"},
+			want: []Candidate{
+				{Context: "go", Score: 1, Markers: []string{"go.mod", "go.sum"}},
+				{Context: "node", Score: 0.5, Markers: []string{"package.json"}, Distance: 1},
+			},
+		},
+		{
+			name:  "closest directory is kept per context",
+			files: []string{"go.mod", "sub/go.mod"},
+			want: []Candidate{
+				{Context: "go", Score: 1, Markers: []string{"go.mod"}},
+			},
+		},
+		{
+			name:  "ties follow priority order",
+			files: []string{"sub/Makefile", "sub/package.json"},
+			want: []Candidate{
+				{Context: "node", Score: 1, Markers: []string{"package.json"}},
+				{Context: "make", Score: 1, Markers: []string{"Makefile"}},
+			},
+		},
+		{
+			name:   "sources win ties",
+			files:  []string{"sub/go.mod"},
+			source: "docker",
+			want: []Candidate{
+				{Context: "docker", Score: 1, Plugin: "fake"},
+				{Context: "go", Score: 1, Markers: []string{"go.mod"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			sub := filepath.Join(root, "sub")
+			if err := os.MkdirAll(sub, 0755); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(root, file), []byte("test"), 0644); err != nil {
+					t.Fatalf("failed to create %s: %v", file, err)
+				}
+			}
+
+			detector := NewDetector()
+			if tt.source != "" {
+				detector.AddSource(fakeSource{dir: sub, ctx: tt.source})
+			}
+			got, err := detector.DetectAll(sub)
+			if err != nil {
+				t.Fatalf("DetectAll() unexpected error: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("DetectAll() = %+v, want %d candidates", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				want.Dir = sub
+				if want.Distance > 0 {
+					want.Dir = root
+				}
+				if !reflect.DeepEqual(got[i], want) {
+					t.Errorf("candidate %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestDetector_DetectAll_NoContext tests that no candidates is not an error
+func TestDetector_DetectAll_NoContext(t *testing.T) {
+	got, err := NewDetector().DetectAll(t.TempDir())
+	if err != nil {
+		t.Fatalf("DetectAll() unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("DetectAll() = %+v, want no candidates", got)
+	}
+}
+
 // Benchmark tests
 func BenchmarkDetector_Detect(b *testing.B) {
 	tmpDir := b.TempDir()
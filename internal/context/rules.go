@@ -0,0 +1,292 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxRuleReadSize is how much of a file key and match rules read. A key
+// rule does not match a larger file; a match rule only sees its beginning.
+const MaxRuleReadSize = 1 << 20
+
+// Rule is a detection rule: a file, named exactly or by a glob pattern, that
+// must exist and, optionally, contain a key or match a regular expression.
+// Marker files are rules with only File set.
+type Rule struct {
+	// File is a file name or glob pattern relative to the searched
+	// directory, such as go.mod, *.csproj or k8s/*.yaml
+	File string `yaml:"file" json:"file"`
+
+	// Key is a dotted key path, such as dependencies.next, that must exist
+	// in the file. The file is read as JSON, TOML or YAML.
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+
+	// Match is a regular expression the file must match. With Key, it must
+	// match the value at Key instead.
+	Match string `yaml:"match,omitempty" json:"match,omitempty"`
+
+	// Format is json, toml or yaml; by default it follows the extension
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Secondary rules only accompany a project (go.sum) and score lower
+	// than rules that define one (go.mod)
+	Secondary bool `yaml:"secondary,omitempty" json:"secondary,omitempty"`
+}
+
+// String describes the rule, such as "package.json key dependencies.next"
+func (r Rule) String() string {
+	s := r.File
+	if r.Key != "" {
+		s += " key " + r.Key
+	}
+	if r.Match != "" {
+		s += " matching " + r.Match
+	}
+	if r.Secondary {
+		s += " (secondary)"
+	}
+	return s
+}
+
+// Validate checks that the pattern, format and regular expression are valid
+func (r Rule) Validate() error {
+	_, err := compileRule(r)
+	return err
+}
+
+// Find returns the first file in dir the rule matches. An invalid rule
+// matches nothing.
+func (r Rule) Find(dir string) (string, bool) {
+	compiled, err := compileRule(r)
+	if err != nil {
+		return "", false
+	}
+	return compiled.find(dir)
+}
+
+// compiledRule is a validated rule with its regular expression
+type compiledRule struct {
+	Rule
+	match *regexp.Regexp
+}
+
+// compileRule validates a rule and compiles its regular expression
+func compileRule(r Rule) (compiledRule, error) {
+	compiled := compiledRule{Rule: r}
+
+	if r.File == "" {
+		return compiled, errors.New("detection rule needs a file")
+	}
+	if !fs.ValidPath(r.File) || strings.Contains(r.File, `\`) {
+		return compiled, fmt.Errorf("invalid file %q: must be a relative path without '..'", r.File)
+	}
+	if _, err := path.Match(r.File, ""); err != nil {
+		return compiled, fmt.Errorf("invalid file pattern %q: %w", r.File, err)
+	}
+
+	if r.Key != "" {
+		switch r.format() {
+		case "json", "toml", "yaml":
+		case "":
+			return compiled, fmt.Errorf("rule for %q has a key but no format; set format to json, toml or yaml", r.File)
+		default:
+			return compiled, fmt.Errorf("rule for %q has unknown format %q", r.File, r.Format)
+		}
+		for _, part := range strings.Split(r.Key, ".") {
+			if part == "" {
+				return compiled, fmt.Errorf("invalid key %q", r.Key)
+			}
+		}
+	}
+
+	if r.Match != "" {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid match for %q: %w", r.File, err)
+		}
+		compiled.match = re
+	}
+
+	return compiled, nil
+}
+
+// format returns the rule's file format, from Format or the extension
+func (r Rule) format() string {
+	if r.Format != "" {
+		return r.Format
+	}
+	switch strings.ToLower(path.Ext(r.File)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// find returns the first file in dir, in name order, that satisfies the rule
+func (r compiledRule) find(dir string) (string, bool) {
+	matches, err := fs.Glob(os.DirFS(dir), r.File)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range matches {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if fileExists(file) && r.satisfiedBy(file) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// satisfiedBy checks the key and match predicates against a file
+func (r compiledRule) satisfiedBy(file string) bool {
+	if r.Key == "" && r.match == nil {
+		return true
+	}
+
+	data, truncated, err := readBounded(file)
+	if err != nil {
+		return false
+	}
+
+	if r.Key == "" {
+		return r.match.Match(data)
+	}
+
+	if truncated {
+		return false
+	}
+	docs, err := decodeDocuments(data, r.format())
+	if err != nil {
+		return false
+	}
+	for _, doc := range docs {
+		value, found := lookupKey(doc, strings.Split(r.Key, "."))
+		if !found {
+			continue
+		}
+		if r.match == nil || r.match.MatchString(valueString(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readBounded reads up to MaxRuleReadSize bytes of a file and reports
+// whether there was more
+func readBounded(file string) ([]byte, bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxRuleReadSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > MaxRuleReadSize {
+		return data[:MaxRuleReadSize], true, nil
+	}
+	return data, false, nil
+}
+
+// decodeDocuments decodes a file in the given format. YAML files may hold
+// several documents.
+func decodeDocuments(data []byte, format string) ([]interface{}, error) {
+	switch format {
+	case "json":
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return []interface{}{doc}, nil
+	case "toml":
+		doc, err := ParseTOML(data)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{doc}, nil
+	case "yaml":
+		var docs []interface{}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc interface{}
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				return docs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// lookupKey follows a key path through decoded maps and lists. A number
+// selects a list element; any other key is looked up in every element.
+func lookupKey(doc interface{}, key []string) (interface{}, bool) {
+	if len(key) == 0 {
+		return doc, true
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		value, exists := node[key[0]]
+		if !exists {
+			return nil, false
+		}
+		return lookupKey(value, key[1:])
+	case map[interface{}]interface{}:
+		value, exists := node[key[0]]
+		if !exists {
+			return nil, false
+		}
+		return lookupKey(value, key[1:])
+	case []interface{}:
+		if i, err := strconv.Atoi(key[0]); err == nil {
+			if i < 0 || i >= len(node) {
+				return nil, false
+			}
+			return lookupKey(node[i], key[1:])
+		}
+		for _, element := range node {
+			if value, found := lookupKey(element, key); found {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// valueString returns the text a match is tried against
+func valueString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
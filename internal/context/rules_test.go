@@ -0,0 +1,150 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRule_Find tests globs, key paths and content matches
+func TestRule_Find(t *testing.T) {
+	files := map[string]string{
+		"package.json":          `{"name": "web", "dependencies": {"next": "14.1.0", "react": "18.2.0"}}`,
+		"App.csproj":            `<Project Sdk="Microsoft.NET.Sdk"></Project>`,
+		"infra/main.tf":         `resource "aws_s3_bucket" "b" {}`,
+		"Cargo.toml":            "[package]\nname = \"tool\"\n\n[dependencies]\nserde = { version = \"1\", features = [\"derive\"] }\n",
+		"k8s/app.yaml":          "kind: Service\n---\nkind: Deployment\nspec:\n  replicas: 2\n",
+		"Pipfile":               "[packages]\nrequests = \"*\"\n",
+		"requirements.txt":      "django==4.2\n",
+		"broken.json":           `{"dependencies": `,
+		"settings.d/local.conf": "debug = true\n",
+	}
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		wantFile string
+	}{
+		{name: "exact file", rule: Rule{File: "package.json"}, wantFile: "package.json"},
+		{name: "missing file", rule: Rule{File: "go.mod"}},
+		{name: "glob", rule: Rule{File: "*.csproj"}, wantFile: "App.csproj"},
+		{name: "glob in subdirectory", rule: Rule{File: "infra/*.tf"}, wantFile: "infra/main.tf"},
+		{name: "glob does not recurse", rule: Rule{File: "*.tf"}},
+		{name: "directories do not match", rule: Rule{File: "infra"}},
+		{name: "json key", rule: Rule{File: "package.json", Key: "dependencies.next"}, wantFile: "package.json"},
+		{name: "missing json key", rule: Rule{File: "package.json", Key: "dependencies.vue"}},
+		{name: "json key and value", rule: Rule{File: "package.json", Key: "dependencies.next", Match: `^14\.`}, wantFile: "package.json"},
+		{name: "json value mismatch", rule: Rule{File: "package.json", Key: "dependencies.next", Match: `^13\.`}},
+		{name: "unparsable file", rule: Rule{File: "broken.json", Key: "dependencies"}},
+		{name: "toml key in inline table", rule: Rule{File: "Cargo.toml", Key: "dependencies.serde.version"}, wantFile: "Cargo.toml"},
+		{name: "toml value", rule: Rule{File: "Cargo.toml", Key: "package.name", Match: "^tool$"}, wantFile: "Cargo.toml"},
+		{name: "format for file without extension", rule: Rule{File: "Pipfile", Key: "packages.requests", Format: "toml"}, wantFile: "Pipfile"},
+		{name: "yaml key in any document", rule: Rule{File: "k8s/*.yaml", Key: "spec.replicas"}, wantFile: "k8s/app.yaml"},
+		{name: "yaml value in any document", rule: Rule{File: "k8s/*.yaml", Key: "kind", Match: "^Deployment$"}, wantFile: "k8s/app.yaml"},
+		{name: "content match", rule: Rule{File: "requirements.txt", Match: `(?m)^django\b`}, wantFile: "requirements.txt"},
+		{name: "content mismatch", rule: Rule{File: "requirements.txt", Match: `(?m)^flask\b`}},
+		{name: "content match with glob", rule: Rule{File: "*/*.conf", Match: `debug = true`}, wantFile: "settings.d/local.conf"},
+		{name: "invalid rule matches nothing", rule: Rule{File: "package.json", Match: "("}},
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, found := tt.rule.Find(dir)
+			if found != (tt.wantFile != "") || file != tt.wantFile {
+				t.Errorf("Find() = %q, %v, want %q", file, found, tt.wantFile)
+			}
+		})
+	}
+}
+
+// TestRule_Find_ReadLimit tests that rules read at most MaxRuleReadSize bytes
+func TestRule_Find_ReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	padding := strings.Repeat(" ", MaxRuleReadSize)
+	content := `{"name": "big"}` + padding + "marker"
+	if err := os.WriteFile(filepath.Join(dir, "big.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create big.json: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		found bool
+	}{
+		{name: "match sees the beginning", rule: Rule{File: "big.json", Match: `"big"`}, found: true},
+		{name: "match does not see past the limit", rule: Rule{File: "big.json", Match: "marker"}},
+		{name: "key rules skip large files", rule: Rule{File: "big.json", Key: "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, found := tt.rule.Find(dir); found != tt.found {
+				t.Errorf("Find() found = %v, want %v", found, tt.found)
+			}
+		})
+	}
+}
+
+// TestRule_Validate tests rejecting invalid rules
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		errMsg string
+	}{
+		{name: "valid glob", rule: Rule{File: "*.sln"}},
+		{name: "valid key", rule: Rule{File: "deno.jsonc", Key: "tasks", Format: "json"}},
+		{name: "missing file", rule: Rule{Match: "x"}, errMsg: "needs a file"},
+		{name: "absolute path", rule: Rule{File: "/etc/passwd"}, errMsg: "relative path"},
+		{name: "parent directory", rule: Rule{File: "../go.mod"}, errMsg: "relative path"},
+		{name: "bad pattern", rule: Rule{File: "[.json"}, errMsg: "invalid file pattern"},
+		{name: "key without format", rule: Rule{File: "Pipfile", Key: "packages"}, errMsg: "no format"},
+		{name: "unknown format", rule: Rule{File: "a.ini", Key: "x", Format: "ini"}, errMsg: "unknown format"},
+		{name: "empty key part", rule: Rule{File: "a.json", Key: "a..b"}, errMsg: "invalid key"},
+		{name: "bad regexp", rule: Rule{File: "a.txt", Match: "("}, errMsg: "invalid match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestRule_String tests how rules are described
+func TestRule_String(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{File: "go.mod"}, "go.mod"},
+		{Rule{File: "go.sum", Secondary: true}, "go.sum (secondary)"},
+		{Rule{File: "package.json", Key: "dependencies.next"}, "package.json key dependencies.next"},
+		{Rule{File: "package.json", Key: "dependencies.next", Match: `^14\.`}, `package.json key dependencies.next matching ^14\.`},
+		{Rule{File: "*.csproj", Match: "Sdk"}, "*.csproj matching Sdk"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
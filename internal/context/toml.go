@@ -0,0 +1,40 @@
+package context
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+// ParseTOML reads a TOML document into nested maps. Arrays of tables
+// become []interface{} like every other array, so key paths can be
+// followed through them the same way as through JSON and YAML documents.
+func ParseTOML(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, err
+	}
+	return normalizeTOML(doc).(map[string]interface{}), nil
+}
+
+// normalizeTOML replaces the arrays of tables in a decoded value with
+// []interface{}
+func normalizeTOML(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = normalizeTOML(v)
+		}
+		return value
+	case []map[string]interface{}:
+		list := make([]interface{}, len(value))
+		for i, table := range value {
+			list[i] = normalizeTOML(table)
+		}
+		return list
+	case []interface{}:
+		for i, v := range value {
+			value[i] = normalizeTOML(v)
+		}
+		return value
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package context
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseTOML tests that documents decode into the maps and lists key
+// paths follow
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   map[string]interface{}
+		errMsg string
+	}{
+		{
+			name:  "keys and comments",
+			input: "# comment\ntitle = \"tool\" # trailing\nenabled = true\nport = 8080\n",
+			want:  map[string]interface{}{"title": "tool", "enabled": true, "port": int64(8080)},
+		},
+		{
+			name:  "tables and dotted keys",
+			input: "[tool.poetry]\nname = 'app'\ndependencies.python = \"^3.11\"\n\n[\"quoted key\"]\na = 1",
+			want: map[string]interface{}{
+				"tool":       map[string]interface{}{"poetry": map[string]interface{}{"name": "app", "dependencies": map[string]interface{}{"python": "^3.11"}}},
+				"quoted key": map[string]interface{}{"a": int64(1)},
+			},
+		},
+		{
+			name:  "arrays of tables",
+			input: "[[bin]]\nname = \"a\"\n[[bin]]\nname = \"b\"\n",
+			want: map[string]interface{}{
+				"bin": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+			},
+		},
+		{
+			name:  "multi-line arrays and inline tables",
+			input: "deps = [\n  \"a\", # first\n  { name = \"b\", optional = false },\n]\n",
+			want: map[string]interface{}{
+				"deps": []interface{}{"a", map[string]interface{}{"name": "b", "optional": false}},
+			},
+		},
+		{
+			name:  "multi-line strings",
+			input: "a = \"\"\"\nline one\nline two\"\"\"\nb = '''\nraw \\n'''\n",
+			want:  map[string]interface{}{"a": "line one\nline two", "b": `raw \n`},
+		},
+		{
+			name:   "duplicate key",
+			input:  "a = 1\na = 2\n",
+			errMsg: "line 2",
+		},
+		{
+			name:   "missing value",
+			input:  "a =\n",
+			errMsg: "expected value",
+		},
+		{
+			name:   "unterminated string",
+			input:  "a = \"open\n",
+			errMsg: "cannot contain newlines",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTOML([]byte(tt.input))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ParseTOML() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTOML() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTOML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseTOML_Strings tests string escapes and delimiters against the
+// examples of the TOML spec
+func TestParseTOML_Strings(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		errMsg string
+	}{
+		{name: "short escapes", input: `a = "\b\t\n\f\r\"\\"`, want: "\b\t\n\f\r\"\\"},
+		{name: "escape character", input: `a = "\e[1m"`, want: "\x1b[1m"},
+		{name: "hex escape", input: `a = "caf\xe9"`, want: "café"},
+		{name: "unicode escapes", input: `a = "\u00e9 \U0001F600"`, want: "é 😀"},
+		{name: "Go-only escape", input: `a = "\a"`, errMsg: `invalid escape`},
+		{name: "octal escape", input: `a = "\101"`, errMsg: `invalid escape`},
+		{name: "short unicode escape", input: `a = "\u12"`, errMsg: `hexadecimal digits`},
+		{name: "surrogate", input: `a = "\uD800"`, errMsg: `not valid UTF-8`},
+		{name: "out of range", input: `a = "\U00110000"`, errMsg: `not valid UTF-8`},
+		{name: "literal string", input: `a = 'C:\Users\e'`, want: `C:\Users\e`},
+		{
+			name:  "multi-line escapes",
+			input: "a = \"\"\"\ntab\\there\nquote \\\"\"\" inside\"\"\"",
+			want:  "tab\there\nquote \"\"\" inside",
+		},
+		{
+			name:  "line-ending backslash",
+			input: "a = \"\"\"\nThe quick brown \\\n\n\n  fox jumps over \\   \n    the lazy dog.\"\"\"",
+			want:  "The quick brown fox jumps over the lazy dog.",
+		},
+		{
+			name:  "line-ending backslash with CRLF",
+			input: "a = \"\"\"one \\\r\n   two\"\"\"",
+			want:  "one two",
+		},
+		{
+			name:  "quotes before the closing delimiter",
+			input: `a = """Here are two quotation marks: "". Simple enough.""""`,
+			want:  `Here are two quotation marks: "". Simple enough."`,
+		},
+		{
+			name:  "literal quotes before the closing delimiter",
+			input: `a = '''That's still pointless, she said.'''''`,
+			want:  `That's still pointless, she said.''`,
+		},
+		{name: "multi-line invalid escape", input: "a = \"\"\"\n\\q\"\"\"", errMsg: `invalid escape`},
+		{name: "backslash before text", input: "a = \"\"\"one \\ two\"\"\"", errMsg: `invalid escape`},
+		{name: "invalid escape in a key", input: `"\a" = 1`, errMsg: `invalid escape`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTOML([]byte(tt.input))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ParseTOML() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTOML() unexpected error: %v", err)
+			}
+			if got["a"] != tt.want {
+				t.Errorf("ParseTOML() a = %q, want %q", got["a"], tt.want)
+			}
+		})
+	}
+}
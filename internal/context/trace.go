@@ -0,0 +1,74 @@
+package context
+
+// Trace records every check made while detecting contexts, for diagnosing
+// why a context was or was not picked
+type Trace struct {
+	// Dirs are the searched directories, starting directory first
+	Dirs []DirTrace `json:"directories"`
+
+	// Candidates are the detected contexts, best first, as DetectAll
+	// returns them
+	Candidates []Candidate `json:"candidates"`
+}
+
+// DirTrace records the checks made in one directory
+type DirTrace struct {
+	// Dir is the searched directory
+	Dir string `json:"dir"`
+
+	// Distance is how many levels above the starting directory Dir is
+	Distance int `json:"distance"`
+
+	// Sources are the plugin results, in the order they were asked
+	Sources []SourceCheck `json:"plugins"`
+
+	// Rules are the rule results, by context name
+	Rules []RuleCheck `json:"rules"`
+}
+
+// SourceCheck is the result of asking a source such as a plugin
+type SourceCheck struct {
+	Plugin  string `json:"plugin"`
+	Context string `json:"context,omitempty"`
+	Found   bool   `json:"found"`
+}
+
+// RuleCheck is the result of checking one detection rule
+type RuleCheck struct {
+	Context string `json:"context"`
+	Rule    Rule   `json:"rule"`
+
+	// File is the file that satisfied the rule, if any
+	File  string `json:"file,omitempty"`
+	Found bool   `json:"found"`
+}
+
+// Explain detects contexts like DetectAll and records every directory
+// searched, every plugin asked and every rule checked
+func (d *Detector) Explain(dir string) (*Trace, error) {
+	trace := &Trace{}
+	candidates, err := d.detectAll(dir, trace)
+	if err != nil {
+		return nil, err
+	}
+	trace.Candidates = candidates
+	return trace, nil
+}
+
+// RankReason says why candidate a ranks before b, in the terms of the
+// ranking: score, distance, priority, plugin, built-in order or name
+func RankReason(a, b Candidate) string {
+	switch {
+	case a.Score != b.Score:
+		return "higher score"
+	case a.Distance != b.Distance:
+		return "closer directory"
+	case a.Priority != b.Priority:
+		return "higher priority"
+	case (a.Plugin != "") != (b.Plugin != ""):
+		return "plugins come before marker files"
+	case priority(a.Context) != priority(b.Context):
+		return "built-in order"
+	}
+	return "name order"
+}
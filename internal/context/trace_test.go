@@ -0,0 +1,97 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDetector_Explain tests that every directory, source and rule check is
+// recorded
+func TestDetector_Explain(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "cmd")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatalf("failed to create go.mod: %v", err)
+	}
+
+	detector := &Detector{rules: make(map[string][]compiledRule), priorities: make(map[string]int)}
+	detector.AddMarker("go", "go.mod")
+	if err := detector.AddRule("go", Rule{File: "go.sum", Secondary: true}); err != nil {
+		t.Fatalf("AddRule() unexpected error: %v", err)
+	}
+	detector.AddMarker("node", "package.json")
+	detector.AddSource(fakeSource{dir: sub, ctx: "docker"})
+
+	trace, err := detector.Explain(sub)
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+
+	if len(trace.Dirs) < 2 {
+		t.Fatalf("Explain() searched %d directories, want at least 2", len(trace.Dirs))
+	}
+	for i, dir := range trace.Dirs {
+		if dir.Distance != i {
+			t.Errorf("Dirs[%d].Distance = %d, want %d", i, dir.Distance, i)
+		}
+	}
+
+	here := trace.Dirs[0]
+	if here.Dir != sub {
+		t.Errorf("Dirs[0].Dir = %q, want %q", here.Dir, sub)
+	}
+	wantSources := []SourceCheck{{Plugin: "fake", Context: "docker", Found: true}}
+	if !reflect.DeepEqual(here.Sources, wantSources) {
+		t.Errorf("Dirs[0].Sources = %+v, want %+v", here.Sources, wantSources)
+	}
+
+	parent := trace.Dirs[1]
+	if want := []SourceCheck{{Plugin: "fake"}}; !reflect.DeepEqual(parent.Sources, want) {
+		t.Errorf("Dirs[1].Sources = %+v, want %+v", parent.Sources, want)
+	}
+	wantRules := []RuleCheck{
+		{Context: "go", Rule: Rule{File: "go.mod"}, File: "go.mod", Found: true},
+		{Context: "go", Rule: Rule{File: "go.sum", Secondary: true}},
+		{Context: "node", Rule: Rule{File: "package.json"}},
+	}
+	if !reflect.DeepEqual(parent.Rules, wantRules) {
+		t.Errorf("Dirs[1].Rules = %+v, want %+v", parent.Rules, wantRules)
+	}
+
+	var contexts []string
+	for _, c := range trace.Candidates {
+		contexts = append(contexts, c.Context)
+	}
+	if want := []string{"docker", "go"}; !reflect.DeepEqual(contexts, want) {
+		t.Errorf("Candidates = %v, want %v", contexts, want)
+	}
+}
+
+// TestRankReason tests the reason given for the order of two candidates
+func TestRankReason(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Candidate
+		want string
+	}{
+		{name: "score", a: Candidate{Context: "go", Score: 1}, b: Candidate{Context: "make", Score: 0.5}, want: "higher score"},
+		{name: "distance", a: Candidate{Context: "go", Score: 0.5}, b: Candidate{Context: "make", Score: 0.5, Distance: 1}, want: "closer directory"},
+		{name: "priority", a: Candidate{Context: "web", Score: 1, Priority: 5}, b: Candidate{Context: "node", Score: 1}, want: "higher priority"},
+		{name: "plugin", a: Candidate{Context: "docker", Score: 1, Plugin: "docker"}, b: Candidate{Context: "go", Score: 1}, want: "plugins come before marker files"},
+		{name: "built-in order", a: Candidate{Context: "node", Score: 1}, b: Candidate{Context: "go", Score: 1}, want: "built-in order"},
+		{name: "name", a: Candidate{Context: "alpha", Score: 1}, b: Candidate{Context: "beta", Score: 1}, want: "name order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RankReason(tt.a, tt.b); got != tt.want {
+				t.Errorf("RankReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WalkProject calls visit for dir and its parents, as many as context
+// detection searches, until visit returns true. depth is 0 for dir and one
+// more for each parent. It returns the directory visit stopped at.
+func WalkProject(dir string, visit func(dir string, depth int) bool) (string, bool) {
+	searchDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for depth := 0; depth <= MaxParentLevels; depth++ {
+		if visit(searchDir, depth) {
+			return searchDir, true
+		}
+		parent := filepath.Dir(searchDir)
+		if parent == searchDir {
+			break
+		}
+		searchDir = parent
+	}
+	return "", false
+}
+
+// WalkRepository calls visit for dir and its parents until visit returns
+// true. The walk ends after the repository root, so nothing outside the
+// repository is visited, or at the filesystem root outside a repository.
+// It returns the directory visit stopped at.
+func WalkRepository(dir string, visit func(dir string) bool) (string, bool) {
+	searchDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if visit(searchDir) {
+			return searchDir, true
+		}
+		if isRepositoryRoot(searchDir) {
+			return "", false
+		}
+		parent := filepath.Dir(searchDir)
+		if parent == searchDir {
+			return "", false
+		}
+		searchDir = parent
+	}
+}
+
+// RepositoryRoot returns the nearest directory at or above dir that holds
+// .git
+func RepositoryRoot(dir string) (string, bool) {
+	return WalkRepository(dir, isRepositoryRoot)
+}
+
+// isRepositoryRoot reports whether dir is the root of a git repository
+func isRepositoryRoot(dir string) bool {
+	// .git may be a directory or, for worktrees and submodules, a file
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWalkProject tests that the walk visits as many parents as detection
+// searches
+func TestWalkProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	deep := filepath.Join(tmpDir, "a", "b", "c", "d")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+
+	var visited []string
+	var depths []int
+	if _, found := WalkProject(deep, func(dir string, depth int) bool {
+		visited = append(visited, dir)
+		depths = append(depths, depth)
+		return false
+	}); found {
+		t.Error("WalkProject() found a directory, want none")
+	}
+	want := []string{deep, filepath.Dir(deep), filepath.Join(tmpDir, "a", "b"), filepath.Join(tmpDir, "a")}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("WalkProject() visited %q, want %q", visited, want)
+	}
+	if wantDepths := []int{0, 1, 2, 3}; !reflect.DeepEqual(depths, wantDepths) {
+		t.Errorf("WalkProject() passed depths %v, want %v", depths, wantDepths)
+	}
+
+	got, found := WalkProject(deep, func(dir string, _ int) bool {
+		return filepath.Base(dir) == "b"
+	})
+	if !found || got != filepath.Join(tmpDir, "a", "b") {
+		t.Errorf("WalkProject() = %q, %v, want %q", got, found, filepath.Join(tmpDir, "a", "b"))
+	}
+}
+
+// TestWalkRepository tests that the walk stops at the repository root,
+// whether .git is a directory or a file
+func TestWalkRepository(t *testing.T) {
+	for _, gitIsFile := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		repo := filepath.Join(tmpDir, "repo")
+		deep := filepath.Join(repo, "a", "b", "c", "d", "e")
+		if err := os.MkdirAll(deep, 0755); err != nil {
+			t.Fatalf("failed to create directories: %v", err)
+		}
+		var err error
+		if gitIsFile {
+			err = os.WriteFile(filepath.Join(repo, ".git"), []byte("gitdir: ../.git/worktrees/repo\n"), 0644)
+		} else {
+			err = os.Mkdir(filepath.Join(repo, ".git"), 0755)
+		}
+		if err != nil {
+			t.Fatalf("failed to create .git: %v", err)
+		}
+
+		var last string
+		if _, found := WalkRepository(deep, func(dir string) bool {
+			last = dir
+			return false
+		}); found {
+			t.Error("WalkRepository() found a directory, want none")
+		}
+		if last != repo {
+			t.Errorf("WalkRepository() stopped at %q, want the repository root %q", last, repo)
+		}
+
+		if root, found := RepositoryRoot(deep); !found || root != repo {
+			t.Errorf("RepositoryRoot() = %q, %v, want %q", root, found, repo)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bamf0/toolbox/internal/config"
 )
@@ -36,23 +37,23 @@ func (p *DockerPlugin) Version() string {
 func (p *DockerPlugin) Contexts() map[string]config.ContextConfig {
 	return map[string]config.ContextConfig{
 		"docker": {
-			Commands: map[string]string{
-				"build":   "docker build -t $(basename $(pwd)) .",
-				"run":     "docker run -it $(basename $(pwd))",
-				"push":    "docker push $(basename $(pwd))",
-				"compose": "docker-compose up",
-				"stop":    "docker-compose down",
-				"logs":    "docker-compose logs -f",
-				"shell":   "docker exec -it $(docker ps -q -f name=$(basename $(pwd))) /bin/bash",
+			Commands: map[string]config.Command{
+				"build":   {Run: "docker build -t {{.Vars.image}} ."},
+				"run":     {Run: "docker run -it --rm --name {{.Vars.image}} {{.Vars.image}}"},
+				"push":    {Run: "docker push {{.Vars.image}}"},
+				"compose": {Run: "docker-compose up"},
+				"stop":    {Run: "docker-compose down"},
+				"logs":    {Run: "docker-compose logs -f"},
+				"shell":   {Run: "docker exec -it {{.Vars.image}} /bin/bash"},
 			},
 		},
 		"docker-compose": {
-			Commands: map[string]string{
-				"up":      "docker-compose up -d",
-				"down":    "docker-compose down",
-				"logs":    "docker-compose logs -f",
-				"build":   "docker-compose build",
-				"restart": "docker-compose restart",
+			Commands: map[string]config.Command{
+				"up":      {Run: "docker-compose up -d"},
+				"down":    {Run: "docker-compose down"},
+				"logs":    {Run: "docker-compose logs -f"},
+				"build":   {Run: "docker-compose build"},
+				"restart": {Run: "docker-compose restart"},
 			},
 		},
 	}
@@ -77,6 +78,45 @@ func (p *DockerPlugin) Detect(dir string) (string, bool) {
 	return "", false
 }
 
+// Variables provides the image name used by the Docker commands. The "run"
+// command also names the container after the image so "shell" can find it.
+func (p *DockerPlugin) Variables(dir string) map[string]string {
+	return map[string]string{
+		"image": imageName(dir),
+	}
+}
+
+// imageName derives a valid Docker image name from the project directory.
+// Image names must be lowercase and may only contain [a-z0-9._-].
+func imageName(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(absDir)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.' || r == '_' || r == '-':
+			if b.Len() > 0 {
+				b.WriteRune(r)
+			}
+		default:
+			if b.Len() > 0 {
+				b.WriteRune('-')
+			}
+		}
+	}
+
+	name := strings.TrimRight(b.String(), "._-")
+	if name == "" {
+		return "app"
+	}
+	return name
+}
+
 // Validate performs plugin validation
 func (p *DockerPlugin) Validate() error {
 	if p.name == "" {
@@ -94,7 +134,7 @@ func (p *DockerPlugin) Validate() error {
 		}
 
 		for cmdName, cmd := range ctxConfig.Commands {
-			if cmd == "" {
+			if cmd.Run == "" {
 				return fmt.Errorf("context %q, command %q is empty", ctxName, cmdName)
 			}
 		}
@@ -128,23 +168,23 @@ func (p *KubernetesPlugin) Version() string {
 func (p *KubernetesPlugin) Contexts() map[string]config.ContextConfig {
 	return map[string]config.ContextConfig{
 		"kubernetes": {
-			Commands: map[string]string{
-				"apply":        "kubectl apply -f .",
-				"delete":       "kubectl delete -f .",
-				"get":          "kubectl get all",
-				"logs":         "kubectl logs -f",
-				"describe":     "kubectl describe",
-				"exec":         "kubectl exec -it",
-				"port-forward": "kubectl port-forward",
+			Commands: map[string]config.Command{
+				"apply":        {Run: "kubectl apply -f ."},
+				"delete":       {Run: "kubectl delete -f ."},
+				"get":          {Run: "kubectl get all"},
+				"logs":         {Run: "kubectl logs -f"},
+				"describe":     {Run: "kubectl describe"},
+				"exec":         {Run: "kubectl exec -it"},
+				"port-forward": {Run: "kubectl port-forward"},
 			},
 		},
 		"helm": {
-			Commands: map[string]string{
-				"install":  "helm install",
-				"upgrade":  "helm upgrade",
-				"rollback": "helm rollback",
-				"list":     "helm list",
-				"delete":   "helm delete",
+			Commands: map[string]config.Command{
+				"install":  {Run: "helm install"},
+				"upgrade":  {Run: "helm upgrade"},
+				"rollback": {Run: "helm rollback"},
+				"list":     {Run: "helm list"},
+				"delete":   {Run: "helm delete"},
 			},
 		},
 	}
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/bamf0/toolbox/internal/config"
+	contextpkg "github.com/bamf0/toolbox/internal/context"
 )
 
 // Plugin represents a ToolBox plugin that can provide custom contexts and commands.
@@ -35,6 +36,22 @@ type Plugin interface {
 	Validate() error
 }
 
+// VariableProvider is implemented by plugins that contribute template
+// variables to command strings. The values are available as
+// {{.Vars.<name>}} in commands of every context.
+type VariableProvider interface {
+	// Variables returns the plugin's variables for the project rooted at dir
+	Variables(dir string) map[string]string
+}
+
+// RuleProvider is implemented by plugins that detect contexts with
+// detection rules: globs, key paths and regular expressions, as understood
+// by the built-in detector. The rules are ranked with the built-in markers.
+type RuleProvider interface {
+	// DetectRules returns the detection rules of each context
+	DetectRules() map[string][]contextpkg.Rule
+}
+
 // PluginMetadata contains information about a loaded plugin
 type PluginMetadata struct {
 	Name         string
@@ -108,6 +125,16 @@ func (pm *PluginManager) RegisterPlugin(plugin Plugin) error {
 		return fmt.Errorf("plugin validation failed: %w", err)
 	}
 
+	if provider, ok := plugin.(RuleProvider); ok {
+		for ctx, rules := range provider.DetectRules() {
+			for _, rule := range rules {
+				if err := rule.Validate(); err != nil {
+					return fmt.Errorf("plugin validation failed: context %q: %w", ctx, err)
+				}
+			}
+		}
+	}
+
 	// Check for name conflicts
 	name := plugin.Name()
 	if _, exists := pm.metadata[name]; exists {
@@ -176,6 +203,26 @@ func (pm *PluginManager) GetContexts() map[string]config.ContextConfig {
 	return allContexts
 }
 
+// Variables collects template variables from all plugins that provide them.
+// When two plugins define the same variable, the first registered plugin wins.
+func (pm *PluginManager) Variables(dir string) map[string]string {
+	vars := make(map[string]string)
+
+	for _, plugin := range pm.plugins {
+		provider, ok := plugin.(VariableProvider)
+		if !ok {
+			continue
+		}
+		for name, value := range provider.Variables(dir) {
+			if _, exists := vars[name]; !exists {
+				vars[name] = value
+			}
+		}
+	}
+
+	return vars
+}
+
 // DetectAllContexts returns all contexts that match the current directory
 func (pm *PluginManager) DetectAllContexts(dir string) []string {
 	var contexts []string
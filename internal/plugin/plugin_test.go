@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
 )
 
 // TestPluginManager_RegisterPlugin tests plugin registration
@@ -58,6 +60,45 @@ func TestPluginManager_DuplicateRegistration(t *testing.T) {
 	}
 }
 
+// rulePlugin is a plugin that detects its context with rules
+type rulePlugin struct {
+	*DockerPlugin
+	rules map[string][]contextpkg.Rule
+}
+
+func (p *rulePlugin) DetectRules() map[string][]contextpkg.Rule {
+	return p.rules
+}
+
+// TestPluginManager_RegisterPlugin_Rules tests validating the rules of rule providers
+func TestPluginManager_RegisterPlugin_Rules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   map[string][]contextpkg.Rule
+		wantErr bool
+	}{
+		{
+			name:  "valid rules",
+			rules: map[string][]contextpkg.Rule{"nextjs": {{File: "package.json", Key: "dependencies.next"}}},
+		},
+		{
+			name:    "invalid rule",
+			rules:   map[string][]contextpkg.Rule{"nextjs": {{File: "../package.json"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPluginManager("/tmp/plugins")
+			err := pm.RegisterPlugin(&rulePlugin{DockerPlugin: NewDockerPlugin(), rules: tt.rules})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RegisterPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestPluginManager_MultiplePlugins tests multiple plugin registration
 func TestPluginManager_MultiplePlugins(t *testing.T) {
 	pm := NewPluginManager("/tmp/plugins")
@@ -311,6 +352,46 @@ func TestPluginManager_DetectContext(t *testing.T) {
 	}
 }
 
+// TestPluginManager_Variables tests collecting template variables from plugins
+func TestPluginManager_Variables(t *testing.T) {
+	pm := NewPluginManager("/tmp/plugins")
+	pm.RegisterPlugin(NewDockerPlugin())
+	pm.RegisterPlugin(NewKubernetesPlugin())
+
+	dir := filepath.Join(t.TempDir(), "My_Service")
+	vars := pm.Variables(dir)
+
+	if vars["image"] != "my_service" {
+		t.Errorf("Variables()[image] = %q, want %q", vars["image"], "my_service")
+	}
+	if len(vars) != 1 {
+		t.Errorf("Variables() returned %d variables, want 1", len(vars))
+	}
+}
+
+// TestImageName tests Docker image names derived from directory names
+func TestImageName(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "/work/myapp", want: "myapp"},
+		{dir: "/work/MyApp", want: "myapp"},
+		{dir: "/work/my app", want: "my-app"},
+		{dir: "/work/_internal.tool", want: "internal.tool"},
+		{dir: "/work/app-", want: "app"},
+		{dir: "/work/@@@", want: "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := imageName(tt.dir); got != tt.want {
+				t.Errorf("imageName(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestPluginManager_AddTrustedHash tests hash allowlist
 func TestPluginManager_AddTrustedHash(t *testing.T) {
 	pm := NewPluginManager("/tmp/plugins")
@@ -81,38 +81,38 @@ func (p *UbuntuPlugin) Contexts() map[string]config.ContextConfig {
 
 	return map[string]config.ContextConfig{
 		"ubuntu-packaging": {
-			Commands: map[string]string{
+			Commands: map[string]config.Command{
 				// Branch creation (takes arguments: project, bug-id, type, description)
-				"gbranch": fmt.Sprintf("bash %s gbranch", scriptPath),
+				"gbranch": {Run: fmt.Sprintf("bash %s gbranch", scriptPath)},
 
 				// PPA-aware commands (infer from current branch)
-				"ppa-status":  fmt.Sprintf("bash %s ppa-status", scriptPath),
-				"ppa-migrate": fmt.Sprintf("bash %s ppa-migrate", scriptPath),
-				"dch-auto":    fmt.Sprintf("bash %s dch-auto", scriptPath),
-				"ubuild":      fmt.Sprintf("bash %s ubuild", scriptPath),
-				"sb-auto":     fmt.Sprintf("bash %s sb-auto", scriptPath),
-				"dput-auto":   fmt.Sprintf("bash %s dput-auto", scriptPath),
+				"ppa-status":  {Run: fmt.Sprintf("bash %s ppa-status", scriptPath)},
+				"ppa-migrate": {Run: fmt.Sprintf("bash %s ppa-migrate", scriptPath)},
+				"dch-auto":    {Run: fmt.Sprintf("bash %s dch-auto", scriptPath)},
+				"ubuild":      {Run: fmt.Sprintf("bash %s ubuild", scriptPath)},
+				"sb-auto":     {Run: fmt.Sprintf("bash %s sb-auto", scriptPath)},
+				"dput-auto":   {Run: fmt.Sprintf("bash %s dput-auto", scriptPath)},
 
 				// Standard changelog commands
-				"dch":         "dch -i",
-				"dch-release": "dch -r",
+				"dch":         {Run: "dch -i"},
+				"dch-release": {Run: "dch -r"},
 
 				// Build commands
-				"build":        "dpkg-buildpackage -us -uc",
-				"build-source": "dpkg-buildpackage -S -us -uc",
+				"build":        {Run: "dpkg-buildpackage -us -uc"},
+				"build-source": {Run: "dpkg-buildpackage -S -us -uc"},
 
 				// Status and info
-				"changelog": "dpkg-parsechangelog",
-				"version":   "dpkg-parsechangelog -S Version",
+				"changelog": {Run: "dpkg-parsechangelog"},
+				"version":   {Run: "dpkg-parsechangelog -S Version"},
 
 				// Clean commands
-				"clean":     "debian/rules clean",
-				"distclean": "fakeroot debian/rules clean",
+				"clean":     {Run: "debian/rules clean"},
+				"distclean": {Run: "fakeroot debian/rules clean"},
 
 				// Linting
-				"lint":         "lintian",
-				"lint-source":  "lintian --pedantic *.dsc",
-				"lint-changes": "lintian --pedantic *.changes",
+				"lint":         {Run: "lintian"},
+				"lint-source":  {Run: "lintian --pedantic *.dsc"},
+				"lint-changes": {Run: "lintian --pedantic *.changes"},
 			},
 			Descriptions: map[string]string{
 				// Branch and PPA management
@@ -0,0 +1,233 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// Plan is everything that runs for one invocation of a command: the command
+// itself, or the steps of a composite command, plus everything they need.
+type Plan struct {
+	// Name is the requested command
+	Name string
+
+	// Context is the context that supplied the command
+	Context string
+
+	// Command is the requested command's own definition
+	Command config.Command
+
+	// Steps are the commands to run, ordered so that every step comes after
+	// the steps it needs
+	Steps []Step
+
+	// ContinueOnError keeps running independent steps after a failure
+	ContinueOnError bool
+
+	// Hooks are the context's hooks, run once around the whole plan
+	Hooks config.Hooks
+
+	// Ambiguous lists other contexts that define the command and rank the
+	// same as the supplying context, when the command came from a fallback
+	Ambiguous []string
+}
+
+// Step is a single runnable command in a plan
+type Step struct {
+	// Name is the command name
+	Name string
+
+	// Context is the context the command was found in, which is the plan's
+	// context or the global context
+	Context string
+
+	// Command is the command definition
+	Command config.Command
+
+	// Needs holds the indexes in Plan.Steps of the steps that must succeed
+	// before this one runs
+	Needs []int
+}
+
+// IsComposite reports whether the plan runs anything besides the requested
+// command itself
+func (p *Plan) IsComposite() bool {
+	return len(p.Steps) != 1 || p.Command.IsComposite()
+}
+
+// Target returns the step for the requested command, or nil for composite
+// commands, which have no command line of their own
+func (p *Plan) Target() *Step {
+	if p.Command.IsComposite() || len(p.Steps) == 0 {
+		return nil
+	}
+	return &p.Steps[len(p.Steps)-1]
+}
+
+// planBuilder collects the steps of a plan and the dependencies between them
+type planBuilder struct {
+	config *config.Config
+	order  []string
+	steps  map[string]*Step
+	needs  map[string]map[string]bool
+	active map[string]bool
+}
+
+// buildPlan resolves a command into its plan
+func buildPlan(cfg *config.Config, context, name string, command config.Command) (*Plan, error) {
+	b := &planBuilder{
+		config: cfg,
+		steps:  make(map[string]*Step),
+		needs:  make(map[string]map[string]bool),
+		active: make(map[string]bool),
+	}
+
+	if err := b.add(context, name, command, nil); err != nil {
+		return nil, err
+	}
+
+	steps, err := b.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{
+		Name:            name,
+		Context:         context,
+		Command:         command,
+		Steps:           steps,
+		ContinueOnError: command.ContinueOnError,
+	}, nil
+}
+
+// add adds a command to the plan. Runnable commands become steps that depend
+// on inherited plus their own needs; composite commands pass both on to each
+// of their steps.
+func (b *planBuilder) add(context, name string, command config.Command, inherited []string) error {
+	key := context + ":" + name
+	if b.active[key] {
+		return fmt.Errorf("dependency cycle at command '%s'", name)
+	}
+	b.active[key] = true
+	defer delete(b.active, key)
+
+	// Everything this command needs runs first, with nothing inherited
+	needs := append([]string(nil), inherited...)
+	for _, ref := range command.Needs {
+		refCtx, refCmd, err := b.resolve(context, name, ref)
+		if err != nil {
+			return err
+		}
+		if err := b.add(refCtx, ref, refCmd, nil); err != nil {
+			return err
+		}
+		leaves, err := b.leaves(refCtx, ref, refCmd)
+		if err != nil {
+			return err
+		}
+		needs = append(needs, leaves...)
+	}
+
+	if command.IsComposite() {
+		for _, ref := range command.Steps {
+			refCtx, refCmd, err := b.resolve(context, name, ref)
+			if err != nil {
+				return err
+			}
+			if err := b.add(refCtx, ref, refCmd, needs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, exists := b.steps[key]; !exists {
+		b.order = append(b.order, key)
+		b.steps[key] = &Step{Name: name, Context: context, Command: command}
+		b.needs[key] = make(map[string]bool)
+	}
+	for _, need := range needs {
+		if need != key {
+			b.needs[key][need] = true
+		}
+	}
+
+	return nil
+}
+
+// leaves returns the keys of the runnable commands a command expands to
+func (b *planBuilder) leaves(context, name string, command config.Command) ([]string, error) {
+	if !command.IsComposite() {
+		return []string{context + ":" + name}, nil
+	}
+
+	var keys []string
+	for _, ref := range command.Steps {
+		refCtx, refCmd, err := b.resolve(context, name, ref)
+		if err != nil {
+			return nil, err
+		}
+		refKeys, err := b.leaves(refCtx, ref, refCmd)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, refKeys...)
+	}
+	return keys, nil
+}
+
+// resolve looks up a step or need referenced by a command
+func (b *planBuilder) resolve(context, from, ref string) (string, config.Command, error) {
+	refCtx, refCmd, ok := b.config.Resolve(context, ref)
+	if !ok {
+		return "", config.Command{}, fmt.Errorf("command '%s' references unknown command '%s'", from, ref)
+	}
+	return refCtx, refCmd, nil
+}
+
+// sort orders the steps so that each comes after everything it needs,
+// keeping the order in which they were added where possible
+func (b *planBuilder) sort() ([]Step, error) {
+	index := make(map[string]int, len(b.order))
+	steps := make([]Step, 0, len(b.order))
+	placed := make(map[string]bool, len(b.order))
+
+	for len(steps) < len(b.order) {
+		progress := false
+		for _, key := range b.order {
+			if placed[key] {
+				continue
+			}
+
+			ready := true
+			for need := range b.needs[key] {
+				if !placed[need] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			step := *b.steps[key]
+			for _, need := range b.order {
+				if b.needs[key][need] {
+					step.Needs = append(step.Needs, index[need])
+				}
+			}
+			index[key] = len(steps)
+			steps = append(steps, step)
+			placed[key] = true
+			progress = true
+			break
+		}
+
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle between steps")
+		}
+	}
+
+	return steps, nil
+}
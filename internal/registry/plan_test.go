@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bamf0/toolbox/internal/config"
+)
+
+// planConfig is a configuration with composite commands shared by the plan tests
+func planConfig() *config.Config {
+	return &config.Config{
+		Contexts: map[string]config.ContextConfig{
+			config.GlobalContext: {
+				Commands: map[string]config.Command{
+					"clean": {Run: "rm -rf tmp"},
+				},
+			},
+			"go": {
+				Commands: map[string]config.Command{
+					"lint":     {Run: "golangci-lint run"},
+					"test":     {Run: "go test ./..."},
+					"generate": {Run: "go generate ./..."},
+					"build":    {Run: "go build ./...", Needs: []string{"generate"}},
+					"ci":       {Steps: []string{"lint", "test", "build"}},
+					"release":  {Steps: []string{"ci", "clean", "build"}, Needs: []string{"fmt"}, ContinueOnError: true},
+					"fmt":      {Run: "gofmt -l ."},
+					"broken":   {Steps: []string{"lint", "nope"}},
+				},
+				Descriptions: map[string]string{
+					"lint": "Run linters",
+				},
+			},
+		},
+	}
+}
+
+// stepNames returns the names of a plan's steps in order
+func stepNames(plan *Plan) []string {
+	names := make([]string, len(plan.Steps))
+	for i, step := range plan.Steps {
+		names[i] = step.Name
+	}
+	return names
+}
+
+// TestRegistry_GetCommand_Plan tests resolving commands into execution plans
+func TestRegistry_GetCommand_Plan(t *testing.T) {
+	reg := New(planConfig())
+
+	tests := []struct {
+		name          string
+		command       string
+		wantSteps     []string
+		wantComposite bool
+	}{
+		{
+			name:      "plain command is a single step",
+			command:   "test",
+			wantSteps: []string{"test"},
+		},
+		{
+			name:          "needs run first",
+			command:       "build",
+			wantSteps:     []string{"generate", "build"},
+			wantComposite: true,
+		},
+		{
+			name:          "composite keeps step order",
+			command:       "ci",
+			wantSteps:     []string{"lint", "test", "generate", "build"},
+			wantComposite: true,
+		},
+		{
+			name:          "nested composite, global step and duplicate step",
+			command:       "release",
+			wantSteps:     []string{"fmt", "lint", "test", "generate", "build", "clean"},
+			wantComposite: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := reg.GetCommand("go", tt.command)
+			if err != nil {
+				t.Fatalf("GetCommand() unexpected error: %v", err)
+			}
+			if got := stepNames(plan); !reflect.DeepEqual(got, tt.wantSteps) {
+				t.Errorf("GetCommand() steps = %q, want %q", got, tt.wantSteps)
+			}
+			if plan.IsComposite() != tt.wantComposite {
+				t.Errorf("IsComposite() = %v, want %v", plan.IsComposite(), tt.wantComposite)
+			}
+		})
+	}
+}
+
+// TestRegistry_GetCommand_PlanDetails tests step contexts, needs and descriptions
+func TestRegistry_GetCommand_PlanDetails(t *testing.T) {
+	reg := New(planConfig())
+
+	plan, err := reg.GetCommand("go", "release")
+	if err != nil {
+		t.Fatalf("GetCommand() unexpected error: %v", err)
+	}
+
+	if !plan.ContinueOnError {
+		t.Error("ContinueOnError = false, want true from the requested command")
+	}
+	if plan.Target() != nil {
+		t.Error("Target() of a composite command should be nil")
+	}
+
+	steps := make(map[string]Step)
+	for _, step := range plan.Steps {
+		steps[step.Name] = step
+	}
+
+	if steps["clean"].Context != config.GlobalContext {
+		t.Errorf("clean context = %q, want %q", steps["clean"].Context, config.GlobalContext)
+	}
+	if steps["lint"].Command.Description != "Run linters" {
+		t.Errorf("lint description = %q, want %q", steps["lint"].Command.Description, "Run linters")
+	}
+
+	// Every step of release inherits its need on fmt; build also needs generate
+	buildNeeds := make([]string, 0)
+	for _, need := range steps["build"].Needs {
+		buildNeeds = append(buildNeeds, plan.Steps[need].Name)
+	}
+	if !reflect.DeepEqual(buildNeeds, []string{"fmt", "generate"}) {
+		t.Errorf("build needs = %q, want [fmt generate]", buildNeeds)
+	}
+	for i, step := range plan.Steps {
+		for _, need := range step.Needs {
+			if need >= i {
+				t.Errorf("step %q needs step %d, which does not run before it", step.Name, need)
+			}
+		}
+	}
+
+	single, err := reg.GetCommand("go", "test")
+	if err != nil {
+		t.Fatalf("GetCommand() unexpected error: %v", err)
+	}
+	if target := single.Target(); target == nil || target.Name != "test" {
+		t.Errorf("Target() = %v, want the test step", target)
+	}
+}
+
+// TestRegistry_GetCommand_UnknownStep tests plans referencing missing commands
+func TestRegistry_GetCommand_UnknownStep(t *testing.T) {
+	reg := New(planConfig())
+
+	_, err := reg.GetCommand("go", "broken")
+	if err == nil || !strings.Contains(err.Error(), "unknown command 'nope'") {
+		t.Errorf("GetCommand() error = %v, want unknown command error", err)
+	}
+}
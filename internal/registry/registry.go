@@ -3,11 +3,21 @@
 package registry
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bamf0/toolbox/internal/config"
 )
 
+var (
+	// ErrUnknownContext is returned for a context that is not configured
+	ErrUnknownContext = errors.New("unknown context")
+
+	// ErrUnknownCommand is returned for a command a context does not define
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
 // Registry manages command lookups across contexts
 type Registry struct {
 	config *config.Config
@@ -21,26 +31,117 @@ func New(cfg *config.Config) *Registry {
 	}
 }
 
-// GetCommand retrieves the full command for a given context and command name.
+// GetCommand resolves a command in a context into its execution plan.
+// A plain command yields a single step; composite commands and commands with
+// needs yield every step that has to run, in dependency order. Each step
+// carries its per-command settings (env, dir, timeout, ...) and a description
+// resolved from its context's descriptions if it has none.
 // Returns an error if the config is nil, context doesn't exist, or command is not found.
-func (r *Registry) GetCommand(context, commandName string) (string, error) {
+func (r *Registry) GetCommand(context, commandName string) (*Plan, error) {
 	if r.config == nil || r.config.Contexts == nil {
-		return "", fmt.Errorf("registry not properly initialized")
+		return nil, fmt.Errorf("registry not properly initialized")
 	}
 
 	// Check if context exists
 	ctxConfig, exists := r.config.Contexts[context]
 	if !exists {
-		return "", fmt.Errorf("unknown context '%s'", context)
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownContext, context)
 	}
 
 	// Check if command exists in context
-	fullCommand, exists := ctxConfig.Commands[commandName]
+	command, exists := ctxConfig.Commands[commandName]
 	if !exists {
-		return "", fmt.Errorf("command '%s' not defined in context '%s'", commandName, context)
+		return nil, fmt.Errorf("%w '%s' in context '%s'", ErrUnknownCommand, commandName, context)
+	}
+
+	command.Description = ctxConfig.Description(commandName)
+
+	plan, err := buildPlan(r.config, context, commandName, command)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		step.Command.Description = r.config.Contexts[step.Context].Description(step.Name)
+	}
+	plan.Hooks = ctxConfig.Hooks
+
+	return plan, nil
+}
+
+// Resolve looks a command up through a chain of contexts and resolves it
+// into its execution plan. The chain is a list of tiers, best first; each tier
+// holds contexts that rank the same, in tie-break order. The first context
+// that defines the command supplies it, and the global context is tried
+// last. Other contexts of the supplying tier that define the command are
+// listed in Plan.Ambiguous. Contexts that are not configured are skipped.
+//
+// Returns ErrUnknownContext if no context of the chain is configured and
+// ErrUnknownCommand if none defines the command.
+func (r *Registry) Resolve(chain [][]string, commandName string) (*Plan, error) {
+	if r.config == nil || r.config.Contexts == nil {
+		return nil, fmt.Errorf("registry not properly initialized")
 	}
 
-	return fullCommand, nil
+	var requested []string
+	configured := false
+	for _, tier := range chain {
+		for _, ctx := range tier {
+			requested = append(requested, ctx)
+			if _, exists := r.config.Contexts[ctx]; exists {
+				configured = true
+			}
+		}
+	}
+	if !configured {
+		return nil, fmt.Errorf("%w %s", ErrUnknownContext, QuoteList(requested))
+	}
+
+	var tried []string
+	seen := make(map[string]bool)
+	tiers := append(append([][]string{}, chain...), []string{config.GlobalContext})
+	for _, tier := range tiers {
+		var defining []string
+		for _, ctx := range tier {
+			ctxConfig, exists := r.config.Contexts[ctx]
+			if !exists || seen[ctx] {
+				continue
+			}
+			seen[ctx] = true
+			tried = append(tried, ctx)
+			if _, defined := ctxConfig.Commands[commandName]; defined {
+				defining = append(defining, ctx)
+			}
+		}
+
+		if len(defining) > 0 {
+			plan, err := r.GetCommand(defining[0], commandName)
+			if err != nil {
+				return nil, err
+			}
+			plan.Ambiguous = defining[1:]
+			return plan, nil
+		}
+	}
+
+	label := "context"
+	if len(tried) > 1 {
+		label = "contexts"
+	}
+	return nil, fmt.Errorf("%w '%s' in %s %s", ErrUnknownCommand, commandName, label, QuoteList(tried))
+}
+
+// QuoteList formats context names as 'a', 'b' and 'c'
+func QuoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
 }
 
 // ListCommands returns all available commands for a context.
@@ -52,7 +153,7 @@ func (r *Registry) ListCommands(context string) ([]string, error) {
 
 	ctxConfig, exists := r.config.Contexts[context]
 	if !exists {
-		return nil, fmt.Errorf("unknown context '%s'", context)
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownContext, context)
 	}
 
 	commands := make([]string, 0, len(ctxConfig.Commands))
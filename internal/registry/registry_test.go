@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/bamf0/toolbox/internal/config"
@@ -11,10 +13,10 @@ func TestRegistry_GetCommand(t *testing.T) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"build": "make all",
-					"test":  "make test",
-					"run":   "./app",
+				Commands: map[string]config.Command{
+					"build": {Run: "make all"},
+					"test":  {Run: "make test"},
+					"run":   {Run: "./app"},
 				},
 			},
 		},
@@ -85,8 +87,8 @@ func TestRegistry_GetCommand(t *testing.T) {
 				if err != nil {
 					t.Errorf("GetCommand() unexpected error: %v", err)
 				}
-				if cmd != tt.wantCommand {
-					t.Errorf("GetCommand() = %q, want %q", cmd, tt.wantCommand)
+				if cmd.Command.Run != tt.wantCommand {
+					t.Errorf("GetCommand() = %q, want %q", cmd.Command.Run, tt.wantCommand)
 				}
 			}
 		})
@@ -98,14 +100,14 @@ func TestRegistry_ListCommands(t *testing.T) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"build": "make all",
-					"test":  "make test",
-					"run":   "./app",
+				Commands: map[string]config.Command{
+					"build": {Run: "make all"},
+					"test":  {Run: "make test"},
+					"run":   {Run: "./app"},
 				},
 			},
 			"empty": {
-				Commands: map[string]string{},
+				Commands: map[string]config.Command{},
 			},
 		},
 	}
@@ -162,9 +164,9 @@ func TestRegistry_ListCommands(t *testing.T) {
 func TestRegistry_ListContexts(t *testing.T) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
-			"node":   {Commands: map[string]string{"build": "npm run build"}},
-			"go":     {Commands: map[string]string{"build": "go build"}},
-			"python": {Commands: map[string]string{"test": "pytest"}},
+			"node":   {Commands: map[string]config.Command{"build": {Run: "npm run build"}}},
+			"go":     {Commands: map[string]config.Command{"build": {Run: "go build"}}},
+			"python": {Commands: map[string]config.Command{"test": {Run: "pytest"}}},
 		},
 	}
 
@@ -232,9 +234,9 @@ func TestRegistry_GetCommand_WithDefaults(t *testing.T) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"node": {
-				Commands: map[string]string{
-					"build": "npm run build",
-					"test":  "npm test",
+				Commands: map[string]config.Command{
+					"build": {Run: "npm run build"},
+					"test":  {Run: "npm test"},
 				},
 			},
 		},
@@ -247,8 +249,8 @@ func TestRegistry_GetCommand_WithDefaults(t *testing.T) {
 		t.Fatalf("GetCommand() unexpected error: %v", err)
 	}
 
-	if cmd != "npm run build" {
-		t.Errorf("GetCommand() = %q, want %q", cmd, "npm run build")
+	if cmd.Command.Run != "npm run build" {
+		t.Errorf("GetCommand() = %q, want %q", cmd.Command.Run, "npm run build")
 	}
 }
 
@@ -257,10 +259,10 @@ func TestRegistry_SpecialCharactersInCommands(t *testing.T) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"complex": "npm run build && npm run test | tee output.log",
-					"quoted":  "echo 'hello world'",
-					"vars":    "GOOS=linux go build",
+				Commands: map[string]config.Command{
+					"complex": {Run: "npm run build && npm run test | tee output.log"},
+					"quoted":  {Run: "echo 'hello world'"},
+					"vars":    {Run: "GOOS=linux go build"},
 				},
 			},
 		},
@@ -296,8 +298,8 @@ func TestRegistry_SpecialCharactersInCommands(t *testing.T) {
 			if err != nil {
 				t.Fatalf("GetCommand() unexpected error: %v", err)
 			}
-			if cmd != tt.wantCommand {
-				t.Errorf("GetCommand() = %q, want %q", cmd, tt.wantCommand)
+			if cmd.Command.Run != tt.wantCommand {
+				t.Errorf("GetCommand() = %q, want %q", cmd.Command.Run, tt.wantCommand)
 			}
 		})
 	}
@@ -308,9 +310,9 @@ func BenchmarkRegistry_GetCommand(b *testing.B) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"build": "make all",
-					"test":  "make test",
+				Commands: map[string]config.Command{
+					"build": {Run: "make all"},
+					"test":  {Run: "make test"},
 				},
 			},
 		},
@@ -328,12 +330,12 @@ func BenchmarkRegistry_ListCommands(b *testing.B) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
 			"test": {
-				Commands: map[string]string{
-					"build":   "make all",
-					"test":    "make test",
-					"deploy":  "./deploy.sh",
-					"clean":   "make clean",
-					"install": "make install",
+				Commands: map[string]config.Command{
+					"build":   {Run: "make all"},
+					"test":    {Run: "make test"},
+					"deploy":  {Run: "./deploy.sh"},
+					"clean":   {Run: "make clean"},
+					"install": {Run: "make install"},
 				},
 			},
 		},
@@ -350,11 +352,11 @@ func BenchmarkRegistry_ListCommands(b *testing.B) {
 func BenchmarkRegistry_ListContexts(b *testing.B) {
 	cfg := &config.Config{
 		Contexts: map[string]config.ContextConfig{
-			"node":   {Commands: map[string]string{"build": "npm run build"}},
-			"go":     {Commands: map[string]string{"build": "go build"}},
-			"python": {Commands: map[string]string{"test": "pytest"}},
-			"rust":   {Commands: map[string]string{"build": "cargo build"}},
-			"java":   {Commands: map[string]string{"build": "mvn package"}},
+			"node":   {Commands: map[string]config.Command{"build": {Run: "npm run build"}}},
+			"go":     {Commands: map[string]config.Command{"build": {Run: "go build"}}},
+			"python": {Commands: map[string]config.Command{"test": {Run: "pytest"}}},
+			"rust":   {Commands: map[string]config.Command{"build": {Run: "cargo build"}}},
+			"java":   {Commands: map[string]config.Command{"build": {Run: "mvn package"}}},
 		},
 	}
 
@@ -365,3 +367,125 @@ func BenchmarkRegistry_ListContexts(b *testing.B) {
 		_ = reg.ListContexts()
 	}
 }
+
+// TestRegistry_GetCommand_ErrorKinds tests that lookup failures can be told apart
+func TestRegistry_GetCommand_ErrorKinds(t *testing.T) {
+	reg := New(&config.Config{
+		Contexts: map[string]config.ContextConfig{
+			"test": {Commands: map[string]config.Command{"build": {Run: "make all"}}},
+		},
+	})
+
+	if _, err := reg.GetCommand("nonexistent", "build"); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("GetCommand() error = %v, want ErrUnknownContext", err)
+	}
+	if _, err := reg.GetCommand("test", "deploy"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("GetCommand() error = %v, want ErrUnknownCommand", err)
+	}
+}
+
+// TestRegistry_Resolve tests looking commands up through a chain of contexts
+func TestRegistry_Resolve(t *testing.T) {
+	reg := New(&config.Config{
+		Contexts: map[string]config.ContextConfig{
+			"docker": {Commands: map[string]config.Command{"build": {Run: "docker build ."}}},
+			"go": {
+				Commands: map[string]config.Command{"build": {Run: "go build"}, "test": {Run: "go test ./..."}},
+				Hooks:    config.Hooks{Before: config.HookList{{Run: "go mod download"}}},
+			},
+			"node":   {Commands: map[string]config.Command{"test": {Run: "npm test"}, "lint": {Run: "npm run lint"}}},
+			"make":   {Commands: map[string]config.Command{"lint": {Run: "make lint"}}},
+			"global": {Commands: map[string]config.Command{"hello": {Run: "echo hello"}}},
+		},
+	})
+
+	tests := []struct {
+		name          string
+		chain         [][]string
+		command       string
+		wantContext   string
+		wantRun       string
+		wantAmbiguous []string
+		wantErr       error
+	}{
+		{
+			name:        "primary context first",
+			chain:       [][]string{{"docker"}, {"go"}},
+			command:     "build",
+			wantContext: "docker",
+			wantRun:     "docker build .",
+		},
+		{
+			name:        "falls back to the next context",
+			chain:       [][]string{{"docker"}, {"go"}, {"node"}},
+			command:     "test",
+			wantContext: "go",
+			wantRun:     "go test ./...",
+		},
+		{
+			name:        "falls back to the global context",
+			chain:       [][]string{{"docker"}, {"go"}},
+			command:     "hello",
+			wantContext: "global",
+			wantRun:     "echo hello",
+		},
+		{
+			name:        "unconfigured contexts are skipped",
+			chain:       [][]string{{"rust"}, {"go"}},
+			command:     "test",
+			wantContext: "go",
+			wantRun:     "go test ./...",
+		},
+		{
+			name:        "one definition in a tier is not ambiguous",
+			chain:       [][]string{{"docker"}, {"go", "node"}},
+			command:     "build",
+			wantContext: "docker",
+			wantRun:     "docker build .",
+		},
+		{
+			name:          "definitions in the same tier are ambiguous",
+			chain:         [][]string{{"docker"}, {"node", "make"}},
+			command:       "lint",
+			wantContext:   "node",
+			wantRun:       "npm run lint",
+			wantAmbiguous: []string{"make"},
+		},
+		{
+			name:    "unknown command",
+			chain:   [][]string{{"docker"}, {"go"}},
+			command: "deploy",
+			wantErr: ErrUnknownCommand,
+		},
+		{
+			name:    "no configured context",
+			chain:   [][]string{{"rust"}},
+			command: "hello",
+			wantErr: ErrUnknownContext,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := reg.Resolve(tt.chain, tt.command)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if plan.Context != tt.wantContext || plan.Command.Run != tt.wantRun {
+				t.Errorf("Resolve() = %q from %q, want %q from %q", plan.Command.Run, plan.Context, tt.wantRun, tt.wantContext)
+			}
+			if !reflect.DeepEqual(plan.Ambiguous, tt.wantAmbiguous) && len(plan.Ambiguous)+len(tt.wantAmbiguous) > 0 {
+				t.Errorf("Resolve() ambiguous = %v, want %v", plan.Ambiguous, tt.wantAmbiguous)
+			}
+			if tt.wantContext == "go" && len(plan.Hooks.Before) != 1 {
+				t.Errorf("Resolve() hooks = %+v, want the hooks of the supplying context", plan.Hooks)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package shellwords splits command lines into words the way a POSIX shell
+// does, without performing any expansion.
+package shellwords
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Split splits line into words using POSIX shell quoting rules:
+//
+//   - whitespace separates words
+//   - single quotes preserve everything up to the closing quote
+//   - double quotes preserve everything except \", \\, \$, \` and
+//     line continuations
+//   - a backslash outside quotes escapes the next character
+//
+// Nothing is expanded: $VAR, globs and command substitutions stay literal.
+// Template expressions ({{...}}) are copied verbatim, quotes included, so
+// they always stay within a single word.
+func Split(line string) ([]string, error) {
+	return split(line, false)
+}
+
+// SplitTemplate splits line as Split does, for words that are expanded as
+// text/template templates afterwards. Single quotes keep template
+// expressions literal, as they do $VAR in a shell: a {{ inside them is
+// written as {{"{{"}}, which expands to {{ again.
+func SplitTemplate(line string) ([]string, error) {
+	return split(line, true)
+}
+
+// split implements Split and SplitTemplate
+func split(line string, escapeQuoted bool) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case strings.HasPrefix(line[i:], "{{"):
+			end := strings.Index(line[i+2:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated template expression at position %d", i)
+			}
+			word.WriteString(line[i : i+2+end+2])
+			inWord = true
+			i += 2 + end + 1
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case c == '\\':
+			if i+1 >= len(line) {
+				return nil, fmt.Errorf("unfinished escape at end of command")
+			}
+			i++
+			if line[i] != '\n' {
+				word.WriteByte(line[i])
+				inWord = true
+			}
+
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unbalanced single quote at position %d", i)
+			}
+			quoted := line[i+1 : i+1+end]
+			if escapeQuoted {
+				quoted = strings.ReplaceAll(quoted, "{{", `{{"{{"}}`)
+			}
+			word.WriteString(quoted)
+			inWord = true
+			i += end + 1
+
+		case c == '"':
+			n, err := readDoubleQuoted(line[i+1:], &word)
+			if err != nil {
+				return nil, fmt.Errorf("unbalanced double quote at position %d", i)
+			}
+			inWord = true
+			i += n
+
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// readDoubleQuoted copies the contents of a double-quoted string to word and
+// returns the number of bytes consumed, including the closing quote
+func readDoubleQuoted(s string, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return i + 1, nil
+
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i+2:], "}}")
+			if end < 0 {
+				return 0, fmt.Errorf("unterminated template expression")
+			}
+			word.WriteString(s[i : i+2+end+2])
+			i += 2 + end + 1
+
+		case c == '\\' && i+1 < len(s):
+			switch next := s[i+1]; next {
+			case '"', '\\', '$', '`':
+				word.WriteByte(next)
+				i++
+			case '\n':
+				i++
+			default:
+				word.WriteByte(c)
+			}
+
+		default:
+			word.WriteByte(c)
+		}
+	}
+
+	return 0, fmt.Errorf("unbalanced double quote")
+}
+
+// Join quotes each word where needed and joins them with spaces, so that
+// Split(Join(words)) returns words again
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = Quote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Quote returns word unchanged if it has no characters that are special to
+// Split or to a shell, and single-quoted otherwise
+func Quote(word string) string {
+	if word == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(word, " \t\n\r'\"\\{}$`;&|<>()*?#~") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,189 @@
+package shellwords
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSplit tests splitting command lines into words
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{
+			name: "plain words",
+			line: "go test ./...",
+			want: []string{"go", "test", "./..."},
+		},
+		{
+			name: "extra whitespace",
+			line: "  npm\trun   build \n",
+			want: []string{"npm", "run", "build"},
+		},
+		{
+			name: "empty line",
+			line: "",
+			want: nil,
+		},
+		{
+			name: "single quotes",
+			line: "go test -run 'TestFoo Bar'",
+			want: []string{"go", "test", "-run", "TestFoo Bar"},
+		},
+		{
+			name: "double quotes",
+			line: `git commit -m "two words"`,
+			want: []string{"git", "commit", "-m", "two words"},
+		},
+		{
+			name: "quotes inside a word",
+			line: `--name="my app" pre'fix'suffix`,
+			want: []string{"--name=my app", "prefixsuffix"},
+		},
+		{
+			name: "empty quoted word",
+			line: `echo "" ''`,
+			want: []string{"echo", "", ""},
+		},
+		{
+			name: "backslash escapes outside quotes",
+			line: `echo a\ b \"c\" \\`,
+			want: []string{"echo", "a b", `"c"`, `\`},
+		},
+		{
+			name: "backslash in double quotes",
+			line: `echo "a \"b\" \$HOME \n"`,
+			want: []string{"echo", `a "b" $HOME \n`},
+		},
+		{
+			name: "backslash in single quotes is literal",
+			line: `echo 'a\b'`,
+			want: []string{"echo", `a\b`},
+		},
+		{
+			name: "line continuation",
+			line: "go test \\\n  ./...",
+			want: []string{"go", "test", "./..."},
+		},
+		{
+			name: "nothing is expanded",
+			line: "echo $HOME *.go $(id) ; ls",
+			want: []string{"echo", "$HOME", "*.go", "$(id)", ";", "ls"},
+		},
+		{
+			name: "template with spaces stays one word",
+			line: `docker build -t {{ index .Vars "image" }} .`,
+			want: []string{"docker", "build", "-t", `{{ index .Vars "image" }}`, "."},
+		},
+		{
+			name: "template inside double quotes",
+			line: `git tag -m "release {{ .GitCommit }}"`,
+			want: []string{"git", "tag", "-m", "release {{ .GitCommit }}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.line)
+			if err != nil {
+				t.Fatalf("Split(%q) unexpected error: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSplitTemplate tests that single quotes keep template expressions
+// literal and other quoting leaves them to be expanded
+func TestSplitTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{
+			name: "single-quoted format string",
+			line: `docker ps --format '{{.Names}}'`,
+			want: []string{"docker", "ps", "--format", `{{"{{"}}.Names}}`},
+		},
+		{
+			name: "quoted part of a word",
+			line: `kubectl get pods -o=go-template='{{range .items}}{{.metadata.name}}{{end}}'`,
+			want: []string{"kubectl", "get", "pods", `-o=go-template={{"{{"}}range .items}}{{"{{"}}.metadata.name}}{{"{{"}}end}}`},
+		},
+		{
+			name: "double quotes and bare words expand",
+			line: `echo "{{ .ProjectName }}" {{arg 1}}`,
+			want: []string{"echo", "{{ .ProjectName }}", "{{arg 1}}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitTemplate(tt.line)
+			if err != nil {
+				t.Fatalf("SplitTemplate(%q) unexpected error: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitTemplate(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSplit_Errors tests rejection of malformed command lines
+func TestSplit_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		errMsg string
+	}{
+		{name: "unbalanced single quote", line: "go test -run 'TestFoo", errMsg: "unbalanced single quote"},
+		{name: "unbalanced double quote", line: `git commit -m "oops`, errMsg: "unbalanced double quote"},
+		{name: "escaped closing quote", line: `echo "a\"`, errMsg: "unbalanced double quote"},
+		{name: "trailing backslash", line: `echo \`, errMsg: "unfinished escape"},
+		{name: "unterminated template", line: "echo {{ .ProjectName", errMsg: "unterminated template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(tt.line)
+			if err == nil {
+				t.Fatalf("Split(%q) expected error, got nil", tt.line)
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Split(%q) error = %v, want error containing %q", tt.line, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestJoin tests that joined words split back into the same words
+func TestJoin(t *testing.T) {
+	tests := [][]string{
+		{"go", "test", "./..."},
+		{"-m", "two words"},
+		{"it's", `say "hi"`, `back\slash`},
+		{"", "$HOME", "a;b"},
+	}
+
+	for _, words := range tests {
+		joined := Join(words)
+		got, err := Split(joined)
+		if err != nil {
+			t.Fatalf("Split(Join(%q)) unexpected error: %v", words, err)
+		}
+		if !reflect.DeepEqual(got, words) {
+			t.Errorf("Split(Join(%q)) = %q (joined %s)", words, got, joined)
+		}
+	}
+
+	if got := Join([]string{"go", "test", "-run", "Foo Bar"}); got != "go test -run 'Foo Bar'" {
+		t.Errorf("Join() = %s, want %s", got, "go test -run 'Foo Bar'")
+	}
+}
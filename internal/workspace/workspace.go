@@ -0,0 +1,338 @@
+// Package workspace finds the projects of a monorepo so that a command can
+// run in each of them. Projects are listed in a .toolbox-workspace.yaml file
+// or found by scanning the directory tree for detected contexts.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	contextpkg "github.com/bamf0/toolbox/internal/context"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FileName is the file that marks a workspace root and lists its projects
+	FileName = ".toolbox-workspace.yaml"
+
+	// MaxFileSize limits the workspace file size
+	MaxFileSize = 64 * 1024
+
+	// MaxProjects limits the projects of a workspace
+	MaxProjects = 200
+
+	// MaxPatterns limits the project and exclude patterns of a workspace file
+	MaxPatterns = 100
+
+	// DefaultDepth is how many levels below the root a scan looks for projects
+	DefaultDepth = 3
+
+	// MaxDepth bounds the scan depth
+	MaxDepth = 10
+)
+
+// skippedDirs are never scanned: they hold dependencies and build output,
+// not projects
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// Workspace is a directory tree whose projects a command can run across
+type Workspace struct {
+	// Root is the absolute path of the workspace directory
+	Root string `yaml:"-"`
+
+	// File is the workspace file, or "" when there is none and projects
+	// are found by scanning from the working directory
+	File string `yaml:"-"`
+
+	// Projects are directories or glob patterns, relative to Root, of the
+	// projects. Without them, the tree is scanned.
+	Projects []string `yaml:"projects,omitempty"`
+
+	// Depth is how many levels below Root a scan looks for projects
+	Depth int `yaml:"depth,omitempty"`
+
+	// Exclude are directories or glob patterns, relative to Root, that are
+	// not projects and are not scanned
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Project is a directory of the workspace that a command runs in
+type Project struct {
+	// Path is the project directory relative to the workspace root, with
+	// forward slashes; the root itself is "."
+	Path string
+
+	// Dir is the absolute project directory
+	Dir string
+}
+
+// Find returns the workspace around dir: the nearest directory, from dir
+// up to the repository root, that holds a workspace file. Without one, dir
+// is the root of a scanned workspace.
+func Find(dir string) (*Workspace, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	rootDir, found := contextpkg.WalkRepository(absDir, func(searchDir string) bool {
+		_, err := os.Stat(filepath.Join(searchDir, FileName))
+		return err == nil
+	})
+	if found {
+		return Load(filepath.Join(rootDir, FileName))
+	}
+
+	return &Workspace{Root: absDir, Depth: DefaultDepth}, nil
+}
+
+// Load reads a workspace file. The workspace root is the file's directory.
+func Load(file string) (*Workspace, error) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	info, err := os.Stat(absFile)
+	if err != nil {
+		return nil, fmt.Errorf("workspace file not accessible: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("workspace file must be a regular file")
+	}
+	if info.Size() > MaxFileSize {
+		return nil, fmt.Errorf("workspace file exceeds maximum size of %d bytes (got %d bytes)", MaxFileSize, info.Size())
+	}
+
+	data, err := os.ReadFile(absFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace file: %w", err)
+	}
+
+	var w Workspace
+	if err := yaml.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace file: invalid YAML format")
+	}
+	w.Root = filepath.Dir(absFile)
+	w.File = absFile
+	if w.Depth == 0 {
+		w.Depth = DefaultDepth
+	}
+
+	if err := w.validate(); err != nil {
+		return nil, fmt.Errorf("invalid workspace file: %w", err)
+	}
+	return &w, nil
+}
+
+// validate checks the depth and every pattern of a workspace file
+func (w *Workspace) validate() error {
+	if w.Depth < 0 || w.Depth > MaxDepth {
+		return fmt.Errorf("depth must be between 1 and %d (got %d)", MaxDepth, w.Depth)
+	}
+	if len(w.Projects)+len(w.Exclude) > MaxPatterns {
+		return fmt.Errorf("too many project and exclude patterns (max: %d)", MaxPatterns)
+	}
+	for _, pattern := range w.Projects {
+		if err := ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("project %w", err)
+		}
+	}
+	for _, pattern := range w.Exclude {
+		if err := ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("exclude %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidatePattern checks that a project pattern is a valid glob pattern
+// relative to the workspace root
+func ValidatePattern(pattern string) error {
+	clean := strings.TrimSuffix(pattern, "/")
+	if !fs.ValidPath(clean) || strings.Contains(clean, `\`) {
+		return fmt.Errorf("pattern %q must be a relative path without '..'", pattern)
+	}
+	if _, err := path.Match(clean, ""); err != nil {
+		return fmt.Errorf("pattern %q is invalid: %w", pattern, err)
+	}
+	return nil
+}
+
+// Discover returns the projects of the workspace, sorted by path. Listed
+// projects are used as they are; otherwise every directory down to Depth
+// that isProject accepts is a project, including the root. Hidden
+// directories and dependency directories such as node_modules are skipped.
+func (w *Workspace) Discover(isProject func(dir string) bool) ([]Project, error) {
+	var paths []string
+	var err error
+	if len(w.Projects) > 0 {
+		paths, err = w.listed()
+	} else {
+		paths, err = w.scan(isProject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > MaxProjects {
+		return nil, fmt.Errorf("workspace has more than %d projects", MaxProjects)
+	}
+
+	sort.Strings(paths)
+	projects := make([]Project, 0, len(paths))
+	for _, p := range paths {
+		projects = append(projects, Project{Path: p, Dir: filepath.Join(w.Root, filepath.FromSlash(p))})
+	}
+	return projects, nil
+}
+
+// listed returns the directories the project patterns match. A pattern
+// that matches no directory is an error, so that a typo is not silently
+// ignored.
+func (w *Workspace) listed() ([]string, error) {
+	root := os.DirFS(w.Root)
+	seen := make(map[string]bool)
+	var paths []string
+
+	for _, pattern := range w.Projects {
+		matches, err := fs.Glob(root, strings.TrimSuffix(pattern, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid project pattern %q: %w", pattern, err)
+		}
+
+		found := false
+		for _, match := range matches {
+			info, err := fs.Stat(root, match)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			found = true
+			if seen[match] || w.excluded(match) {
+				continue
+			}
+			seen[match] = true
+			paths = append(paths, match)
+		}
+		if !found {
+			return nil, fmt.Errorf("project %q matches no directory in %s", pattern, w.Root)
+		}
+	}
+	return paths, nil
+}
+
+// errTooManyProjects stops a scan early
+var errTooManyProjects = errors.New("too many projects")
+
+// scan walks the tree below the root for directories isProject accepts
+func (w *Workspace) scan(isProject func(dir string) bool) ([]string, error) {
+	var paths []string
+
+	err := fs.WalkDir(os.DirFS(w.Root), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// An unreadable directory holds no projects we can run in
+			if p != "." {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if p != "." {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || skippedDirs[name] || w.excluded(p) {
+				return fs.SkipDir
+			}
+		}
+
+		if isProject(filepath.Join(w.Root, filepath.FromSlash(p))) {
+			paths = append(paths, p)
+			if len(paths) > MaxProjects {
+				return errTooManyProjects
+			}
+		}
+
+		if depth(p) >= w.Depth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, errTooManyProjects) {
+		return paths, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan workspace: %w", err)
+	}
+	return paths, nil
+}
+
+// excluded reports whether an exclude pattern matches p or a directory
+// above it
+func (w *Workspace) excluded(p string) bool {
+	for _, pattern := range w.Exclude {
+		if MatchPath(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// depth returns how many levels below the root p is
+func depth(p string) int {
+	if p == "." {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
+
+// MatchPath reports whether a glob pattern matches a project path or one of
+// the directories above it, so that "services" matches "services/api"
+func MatchPath(pattern, p string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	for {
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+		parent := path.Dir(p)
+		if parent == p || parent == "." {
+			return false
+		}
+		p = parent
+	}
+}
+
+// Filter returns the projects that match at least one of the patterns, or
+// every project when there are no patterns
+func Filter(projects []Project, patterns []string) ([]Project, error) {
+	if len(patterns) == 0 {
+		return projects, nil
+	}
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	var filtered []Project
+	for _, p := range projects {
+		for _, pattern := range patterns {
+			if MatchPath(pattern, p.Path) {
+				filtered = append(filtered, p)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,264 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files, with their directories, below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+}
+
+// hasMarker accepts directories that contain a file named "marker"
+func hasMarker(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "marker"))
+	return err == nil
+}
+
+// projectPaths returns the paths of projects
+func projectPaths(projects []Project) []string {
+	paths := []string{}
+	for _, p := range projects {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+// TestFind tests locating the workspace file from a subdirectory
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		start    string
+		wantRoot string
+		wantFile bool
+	}{
+		{
+			name:     "file in start directory",
+			files:    map[string]string{FileName: "projects: [api]\n", "api/marker": ""},
+			start:    ".",
+			wantRoot: ".",
+			wantFile: true,
+		},
+		{
+			name:     "file in parent",
+			files:    map[string]string{FileName: "depth: 2\n", "services/api/marker": ""},
+			start:    "services/api",
+			wantRoot: ".",
+			wantFile: true,
+		},
+		{
+			name:     "no file scans from start directory",
+			files:    map[string]string{"services/api/marker": ""},
+			start:    "services",
+			wantRoot: "services",
+		},
+		{
+			name:     "search stops at repository root",
+			files:    map[string]string{FileName: "depth: 2\n", "repo/.git/HEAD": "", "repo/app/marker": ""},
+			start:    "repo/app",
+			wantRoot: "repo/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			w, err := Find(filepath.Join(dir, tt.start))
+			if err != nil {
+				t.Fatalf("Find() unexpected error: %v", err)
+			}
+			if want := filepath.Join(dir, tt.wantRoot); w.Root != want {
+				t.Errorf("Root = %q, want %q", w.Root, want)
+			}
+			if (w.File != "") != tt.wantFile {
+				t.Errorf("File = %q, want file: %v", w.File, tt.wantFile)
+			}
+			if w.Depth < 1 {
+				t.Errorf("Depth = %d, want a positive default", w.Depth)
+			}
+		})
+	}
+}
+
+// TestLoad_Invalid tests that invalid workspace files are rejected
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "invalid yaml", content: "projects: [", wantErr: "invalid YAML"},
+		{name: "depth too large", content: "depth: 50\n", wantErr: "depth must be between"},
+		{name: "negative depth", content: "depth: -1\n", wantErr: "depth must be between"},
+		{name: "absolute project", content: "projects: [/etc]\n", wantErr: "relative path"},
+		{name: "traversal", content: "projects: [../other]\n", wantErr: "relative path"},
+		{name: "invalid pattern", content: "projects: ['services/[']\n", wantErr: "invalid"},
+		{name: "invalid exclude", content: "exclude: ['..']\n", wantErr: "exclude pattern"},
+		{name: "too many patterns", content: "projects: [" + strings.Repeat("a,", MaxPatterns) + "a]\n", wantErr: "too many"},
+		{name: "too large", content: "# " + strings.Repeat("x", MaxFileSize) + "\n", wantErr: "maximum size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{FileName: tt.content})
+
+			_, err := Load(filepath.Join(dir, FileName))
+			if err == nil {
+				t.Fatalf("Load() expected error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWorkspace_Discover tests listed and scanned projects
+func TestWorkspace_Discover(t *testing.T) {
+	files := map[string]string{
+		"marker":                      "",
+		"services/api/marker":         "",
+		"services/worker/marker":      "",
+		"services/legacy/marker":      "",
+		"services/README.md":          "",
+		"web/marker":                  "",
+		"web/node_modules/dep/marker": "",
+		"tools/scripts/lint/marker":   "",
+		".cache/tool/marker":          "",
+		"docs/guide.md":               "",
+	}
+
+	tests := []struct {
+		name      string
+		workspace Workspace
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "scan",
+			workspace: Workspace{Depth: DefaultDepth},
+			want:      []string{".", "services/api", "services/legacy", "services/worker", "tools/scripts/lint", "web"},
+		},
+		{
+			name:      "scan depth",
+			workspace: Workspace{Depth: 2},
+			want:      []string{".", "services/api", "services/legacy", "services/worker", "web"},
+		},
+		{
+			name:      "scan with exclude",
+			workspace: Workspace{Depth: DefaultDepth, Exclude: []string{"services/legacy", "tools"}},
+			want:      []string{".", "services/api", "services/worker", "web"},
+		},
+		{
+			name:      "listed patterns",
+			workspace: Workspace{Projects: []string{"services/*", "web/"}, Exclude: []string{"services/legacy"}},
+			want:      []string{"services/api", "services/worker", "web"},
+		},
+		{
+			name:      "listed directory without marker",
+			workspace: Workspace{Projects: []string{"docs"}},
+			want:      []string{"docs"},
+		},
+		{
+			name:      "listed twice",
+			workspace: Workspace{Projects: []string{"web", "w*"}},
+			want:      []string{"web"},
+		},
+		{
+			name:      "pattern without match",
+			workspace: Workspace{Projects: []string{"frontend"}},
+			wantErr:   `project "frontend" matches no directory`,
+		},
+		{
+			name:      "files are not projects",
+			workspace: Workspace{Projects: []string{"services/README.md"}},
+			wantErr:   "matches no directory",
+		},
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.workspace
+			w.Root = dir
+
+			projects, err := w.Discover(hasMarker)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Discover() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Discover() unexpected error: %v", err)
+			}
+
+			if got := projectPaths(projects); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %v, want %v", got, tt.want)
+			}
+			for _, p := range projects {
+				if p.Dir != filepath.Join(dir, filepath.FromSlash(p.Path)) {
+					t.Errorf("project %s has Dir %q", p.Path, p.Dir)
+				}
+			}
+		})
+	}
+}
+
+// TestFilter tests selecting projects by path patterns
+func TestFilter(t *testing.T) {
+	projects := []Project{{Path: "."}, {Path: "services/api"}, {Path: "services/worker"}, {Path: "web"}}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "no patterns", want: []string{".", "services/api", "services/worker", "web"}},
+		{name: "glob", patterns: []string{"services/*"}, want: []string{"services/api", "services/worker"}},
+		{name: "parent directory", patterns: []string{"services"}, want: []string{"services/api", "services/worker"}},
+		{name: "trailing slash", patterns: []string{"services/"}, want: []string{"services/api", "services/worker"}},
+		{name: "several patterns", patterns: []string{"web", "*/api"}, want: []string{"services/api", "web"}},
+		{name: "root", patterns: []string{"."}, want: []string{"."}},
+		{name: "no match", patterns: []string{"mobile"}, want: []string{}},
+		{name: "invalid pattern", patterns: []string{"../web"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Filter(projects, tt.patterns)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Filter() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Filter() unexpected error: %v", err)
+			}
+			if paths := projectPaths(got); !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Filter() = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}